@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// flatten flattens the tree into its leaf values keyed by their dotted path.
+// Values are JSON encoded, lists are compared as a whole.
+func flatten(tree map[string]interface{}) map[string]string {
+	out := map[string]string{}
+
+	var walk func(path string, val interface{})
+	walk = func(path string, val interface{}) {
+		if m, ok := val.(map[string]interface{}); ok && len(m) > 0 {
+			for key, item := range m {
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, item)
+			}
+			return
+		}
+
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(val))
+		}
+		out[path] = string(encoded)
+	}
+
+	for key, val := range tree {
+		walk(key, val)
+	}
+
+	return out
+}
+
+// diffValues returns the changes from a to b, sorted by path.
+// Removed values are prefixed with -, added with + and changed with ~.
+func diffValues(a, b map[string]string) []string {
+	paths := make([]string, 0, len(a)+len(b))
+	for path := range a {
+		paths = append(paths, path)
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []string
+	for _, path := range paths {
+		oldVal, inA := a[path]
+		newVal, inB := b[path]
+
+		switch {
+		case !inB:
+			changes = append(changes, fmt.Sprintf("- %s: %s", path, oldVal))
+		case !inA:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", path, newVal))
+		case oldVal != newVal:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", path, oldVal, newVal))
+		}
+	}
+
+	return changes
+}
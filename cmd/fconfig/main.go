@@ -0,0 +1,411 @@
+// Command fconfig validates and inspects configuration files loaded with the
+// fconfig package, e.g. to catch broken configs in a deploy pipeline before
+// rolling them out.
+//
+// Usage:
+//
+//	fconfig validate [flags] <file>
+//	fconfig render [flags] <file>
+//	fconfig secrets [flags] <file>
+//	fconfig diff [flags] <a> <b>
+//
+// The files are loaded the same way as fconfig.LoadConfig does, with their
+// includes, environment overlay and environment variables expanded. Secrets
+// are not resolved unless --resolve-secrets is set, so the commands do not
+// require access to the secrets by default.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/fconfig"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/spf13/pflag"
+)
+
+// Exit codes of the commands.
+const (
+	exitOK = 0
+
+	// exitFailure is returned when the check of the command fails, e.g. the
+	// config is invalid or the configs differ.
+	exitFailure = 1
+
+	// exitError is returned when the command cannot run, e.g. bad usage or
+	// unreadable file.
+	exitError = 2
+)
+
+// unresolved is the value of the secrets which are not resolved.
+const unresolved = "unresolved"
+
+const usage = `Usage: fconfig <command> [flags] <args>
+
+Commands:
+  validate <file>   parse the config, expand env and check it against a schema
+  render <file>     print the effective config with the secrets redacted
+  secrets <file>    list the referenced secrets and whether they are accessible
+  diff <a> <b>      print the differences between two configs
+
+Run 'fconfig <command> --help' for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command and returns its exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+
+	cmd := &command{name: args[0], stdout: stdout, stderr: stderr}
+
+	switch args[0] {
+	case "validate":
+		return cmd.validate(args[1:])
+	case "render":
+		return cmd.render(args[1:])
+	case "secrets":
+		return cmd.secrets(args[1:])
+	case "diff":
+		return cmd.diff(args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "fconfig: unknown command %q\n\n%s", args[0], usage)
+	return exitError
+}
+
+// command holds the state shared by the subcommands.
+type command struct {
+	name   string
+	stdout io.Writer
+	stderr io.Writer
+
+	envFiles       []string
+	environment    string
+	resolvers      []string
+	resolveSecrets bool
+	timeout        time.Duration
+}
+
+// flagSet returns the flag set of the command with the common flags.
+func (c *command) flagSet(argsUsage string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(c.name, pflag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: fconfig %s [flags] %s\n\nFlags:\n", c.name, argsUsage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringSliceVar(&c.envFiles, "env-file", nil, "env files to load instead of .env")
+	fs.StringVar(&c.environment, "environment", "",
+		"environment of the overlay config file, defaults to APP_ENV or ENV")
+	fs.DurationVar(&c.timeout, "timeout", time.Minute, "maximum duration to load the config")
+	fs.StringSliceVar(&c.resolvers, "resolvers", nil,
+		"opt-in secret schemes to resolve: file, env or vault")
+
+	return fs
+}
+
+// parse parses the flags and checks the number of positional arguments.
+func (c *command) parse(fs *pflag.FlagSet, args []string, nargs int) ([]string, bool) {
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, false
+	}
+
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return nil, false
+	}
+
+	return fs.Args(), true
+}
+
+// options returns the load options of the common flags.
+func (c *command) options(fs *pflag.FlagSet) []fconfig.Option {
+	opts := []fconfig.Option{fconfig.WithTimeout(c.timeout)}
+
+	if len(c.envFiles) > 0 {
+		opts = append(opts, fconfig.WithEnvFiles(c.envFiles...))
+	}
+
+	if fs.Changed("environment") {
+		opts = append(opts, fconfig.WithEnvironment(c.environment))
+	}
+
+	if len(c.resolvers) > 0 {
+		opts = append(opts, fconfig.WithBuiltinResolvers(c.resolvers...))
+	}
+
+	if !c.resolveSecrets {
+		opts = append(opts, unresolvedSecrets(c.resolvers)...)
+	}
+
+	return opts
+}
+
+// load loads the config file into a map.
+func (c *command) load(
+	file string,
+	opts ...fconfig.Option,
+) (*map[string]interface{}, *fconfig.Report, error) {
+	config := map[string]interface{}{}
+	report := &fconfig.Report{}
+
+	opts = append(opts, fconfig.WithReport(report))
+	err := fconfig.LoadConfigWithOptions(context.Background(), file, &config, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &config, report, nil
+}
+
+// fail prints the error and returns the exit code.
+func (c *command) fail(code int, err error) int {
+	fmt.Fprintf(c.stderr, "fconfig %s: %v\n", c.name, err)
+	return code
+}
+
+// validate checks that the config file can be loaded and, if a schema is
+// given, that it matches the schema.
+func (c *command) validate(args []string) int {
+	var (
+		schemaFile string
+		strict     bool
+	)
+
+	fs := c.flagSet("<file>")
+	fs.StringVar(&schemaFile, "schema", "",
+		"JSON Schema file to validate the config against, see fconfig.Schema")
+	fs.BoolVar(&strict, "strict", false, "reject keys which are not described by the schema")
+	fs.BoolVar(&c.resolveSecrets, "resolve-secrets", false, "resolve the secrets as well")
+
+	args, ok := c.parse(fs, args, 1)
+	if !ok {
+		return exitError
+	}
+
+	if strict && schemaFile == "" {
+		return c.fail(exitError, ferrors.NewInvalidArgumentError("--strict requires --schema"))
+	}
+
+	opts := c.options(fs)
+
+	if schemaFile != "" {
+		schema, err := readSchema(schemaFile)
+		if err != nil {
+			return c.fail(exitError, err)
+		}
+
+		opts = append(opts, fconfig.WithSchema(schema))
+		if strict {
+			opts = append(opts, fconfig.WithStrict())
+		}
+	}
+
+	_, report, err := c.load(args[0], opts...)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(c.stderr, "warning: %s\n", warning)
+	}
+
+	fmt.Fprintf(c.stdout, "%s is valid\n", args[0])
+	return exitOK
+}
+
+// render prints the effective config with the secrets redacted.
+func (c *command) render(args []string) int {
+	var format string
+
+	fs := c.flagSet("<file>")
+	fs.StringVarP(&format, "output", "o", "yaml", "output format, yaml or json")
+	fs.BoolVar(&c.resolveSecrets, "resolve-secrets", false,
+		"resolve the secrets, they are redacted anyway")
+
+	args, ok := c.parse(fs, args, 1)
+	if !ok {
+		return exitError
+	}
+
+	dump := fconfig.Dump
+	switch format {
+	case "yaml":
+	case "json":
+		dump = fconfig.DumpJSON
+	default:
+		return c.fail(exitError, ferrors.NewInvalidArgumentError("unknown output format: "+format))
+	}
+
+	config, report, err := c.load(args[0], c.options(fs)...)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	out, err := dump(config, report)
+	if err != nil {
+		return c.fail(exitError, err)
+	}
+
+	_, _ = c.stdout.Write(out)
+	if format == "json" {
+		fmt.Fprintln(c.stdout)
+	}
+
+	return exitOK
+}
+
+// secrets lists the secrets referenced by the config and whether they are
+// accessible.
+func (c *command) secrets(args []string) int {
+	var skipCheck bool
+
+	fs := c.flagSet("<file>")
+	fs.BoolVar(&skipCheck, "no-check", false,
+		"only list the secrets without checking whether they are accessible")
+
+	args, ok := c.parse(fs, args, 1)
+	if !ok {
+		return exitError
+	}
+
+	// the secrets are listed without resolving them, then checked one by one
+	// so an inaccessible secret does not hide the others.
+	_, report, err := c.load(args[0], c.options(fs)...)
+	if err != nil {
+		return c.fail(exitFailure, err)
+	}
+
+	code := exitOK
+	for _, ref := range report.Secrets {
+		if skipCheck {
+			fmt.Fprintln(c.stdout, ref)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		_, err := fconfig.ResolveSecret(ctx, ref, fconfig.WithBuiltinResolvers(c.resolvers...))
+		cancel()
+
+		if err != nil {
+			code = exitFailure
+			fmt.Fprintf(c.stdout, "%s\tinaccessible\t%s\n", ref, ferrors.Code(err))
+			fmt.Fprintf(c.stderr, "%s: %v\n", ref, err)
+			continue
+		}
+
+		fmt.Fprintf(c.stdout, "%s\tok\n", ref)
+	}
+
+	return code
+}
+
+// diff prints the differences between the two configs, with the secrets
+// redacted.
+func (c *command) diff(args []string) int {
+	fs := c.flagSet("<a> <b>")
+
+	args, ok := c.parse(fs, args, 2)
+	if !ok {
+		return exitError
+	}
+
+	trees := make([]map[string]interface{}, len(args))
+	for i, file := range args {
+		config, report, err := c.load(file, c.options(fs)...)
+		if err != nil {
+			return c.fail(exitError, err)
+		}
+
+		trees[i], err = redacted(config, report)
+		if err != nil {
+			return c.fail(exitError, err)
+		}
+	}
+
+	changes := diffValues(flatten(trees[0]), flatten(trees[1]))
+	if len(changes) == 0 {
+		return exitOK
+	}
+
+	fmt.Fprintf(c.stdout, "--- %s\n+++ %s\n", args[0], args[1])
+	for _, change := range changes {
+		fmt.Fprintln(c.stdout, change)
+	}
+
+	return exitFailure
+}
+
+// readSchema reads a JSON Schema file.
+func readSchema(file string) (*fconfig.JSONSchema, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, ferrors.WithStack(err)
+	}
+
+	schema := &fconfig.JSONSchema{}
+	err = json.Unmarshal(content, schema)
+	if err != nil {
+		return nil, ferrors.Wrapf(err, "unable to parse schema file: %s", file)
+	}
+
+	return schema, nil
+}
+
+// redacted returns the loaded config as a tree with the secrets redacted.
+func redacted(
+	config *map[string]interface{},
+	report *fconfig.Report,
+) (map[string]interface{}, error) {
+	out, err := fconfig.DumpJSON(config, report)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := map[string]interface{}{}
+	err = json.Unmarshal(out, &tree)
+	if err != nil {
+		return nil, ferrors.WithStack(err)
+	}
+
+	return tree, nil
+}
+
+// unresolvedSecrets returns options which replace the enabled built-in secret
+// resolvers with ones that do not resolve anything. The values are still
+// recorded as secrets, so they are redacted and listed in the report.
+func unresolvedSecrets(optIn []string) []fconfig.Option {
+	resolver := fconfig.SecretResolverFunc(func(context.Context, string) (string, error) {
+		return unresolved, nil
+	})
+
+	schemes := []string{fconfig.SchemeGSecret, fconfig.SchemeEncrypted}
+	for _, scheme := range optIn {
+		switch scheme {
+		case fconfig.SchemeFile, fconfig.SchemeEnv, fconfig.SchemeVault:
+			schemes = append(schemes, scheme)
+		}
+	}
+
+	opts := make([]fconfig.Option, 0, len(schemes))
+	for _, scheme := range schemes {
+		opts = append(opts, fconfig.WithSecretResolver(scheme, resolver))
+	}
+
+	return opts
+}
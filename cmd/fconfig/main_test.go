@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("FCONFIG_LOCAL_SECRETS", "testdata/secrets.yaml")
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "valid config",
+			args: []string{
+				"validate", "--schema", "testdata/schema.json", "--strict", "testdata/config.yaml",
+			},
+			code:     exitOK,
+			contains: []string{"testdata/config.yaml is valid"},
+		},
+		{
+			name:     "config not matching the schema",
+			args:     []string{"validate", "--schema", "testdata/schema.json", "testdata/invalid.yaml"},
+			code:     exitFailure,
+			contains: []string{"port", "must be of type integer"},
+		},
+		{
+			name: "unknown keys in strict mode",
+			args: []string{
+				"validate", "--schema", "testdata/schema.json", "--strict", "testdata/other.yaml",
+			},
+			code:     exitFailure,
+			contains: []string{"nested.retries", "unknown configuration key"},
+		},
+		{
+			name:     "strict without schema",
+			args:     []string{"validate", "--strict", "testdata/config.yaml"},
+			code:     exitError,
+			contains: []string{"--strict requires --schema"},
+		},
+		{
+			name:     "missing file",
+			args:     []string{"validate", "testdata/nope.yaml"},
+			code:     exitFailure,
+			contains: []string{"nope.yaml"},
+		},
+		{
+			name:     "render redacts the secrets",
+			args:     []string{"render", "--resolve-secrets", "--resolvers", "file", "testdata/config.yaml"},
+			code:     exitOK,
+			contains: []string{"name: service", "dbpassword: '[REDACTED]'", "apikey: '[REDACTED]'"},
+			excludes: []string{"db-password-value", "api-key-value"},
+		},
+		{
+			name:     "render as json",
+			args:     []string{"render", "-o", "json", "testdata/config.yaml"},
+			code:     exitOK,
+			contains: []string{`"port": 8080`, `"dbpassword": "[REDACTED]"`},
+		},
+		{
+			name: "accessible secrets",
+			args: []string{"secrets", "--resolvers", "file", "testdata/config.yaml"},
+			code: exitOK,
+			contains: []string{
+				"file://testdata/api-key.txt\tok",
+				"gSecret://projects/p/secrets/db-password/versions/latest\tok",
+			},
+		},
+		{
+			name:     "inaccessible secret",
+			args:     []string{"secrets", "--resolvers", "file", "testdata/missing.yaml"},
+			code:     exitFailure,
+			contains: []string{"file://testdata/missing.txt\tinaccessible"},
+		},
+		{
+			name: "diff",
+			args: []string{"diff", "testdata/config.yaml", "testdata/other.yaml"},
+			code: exitFailure,
+			contains: []string{
+				"~ port: 8080 -> 9090",
+				"+ nested.retries: 3",
+			},
+			excludes: []string{"dbpassword", "nested.timeout"},
+		},
+		{
+			name: "no diff",
+			args: []string{"diff", "testdata/config.yaml", "testdata/config.yaml"},
+			code: exitOK,
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"lint", "testdata/config.yaml"},
+			code:     exitError,
+			contains: []string{`unknown command "lint"`},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := run(tt.args, &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("expected exit code %d but, got %d\nstdout: %s\nstderr: %s",
+					tt.code, code, stdout.String(), stderr.String())
+			}
+
+			output := stdout.String() + stderr.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(output, s) {
+					t.Errorf("expected output to contain %q but, got:\n%s", s, output)
+				}
+			}
+
+			for _, s := range tt.excludes {
+				if strings.Contains(output, s) {
+					t.Errorf("expected output not to contain %q but, got:\n%s", s, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package fconfig
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// ByteSize is a size in bytes which can be decoded from a human readable
+// string, e.g. 10MiB, 1.5GB or 512.
+//
+// Both decimal (KB, MB, GB, TB, PB) and binary (KiB, MiB, GiB, TiB, PiB)
+// units are supported, units are case insensitive.
+type ByteSize uint64
+
+// Byte sizes.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KB,
+	"kb":  KB,
+	"m":   MB,
+	"mb":  MB,
+	"g":   GB,
+	"gb":  GB,
+	"t":   TB,
+	"tb":  TB,
+	"p":   PB,
+	"pb":  PB,
+	"ki":  KiB,
+	"kib": KiB,
+	"mi":  MiB,
+	"mib": MiB,
+	"gi":  GiB,
+	"gib": GiB,
+	"ti":  TiB,
+	"tib": TiB,
+	"pi":  PiB,
+	"pib": PiB,
+}
+
+// ParseByteSize parses a human readable size, e.g. 10MiB, 1.5GB or 512.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+
+	i := 0
+	for i < len(s) && (s[i] == '.' || ('0' <= s[i] && s[i] <= '9')) {
+		i++
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok || num == "" {
+		return 0, ferrors.NewInvalidArgumentError("invalid byte size: " + s)
+	}
+
+	// parse integers separately to not lose precision with large values.
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(multiplier) {
+			return 0, ferrors.NewInvalidArgumentError("byte size is too large: " + s)
+		}
+		return ByteSize(n) * multiplier, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, ferrors.NewInvalidArgumentError("invalid byte size: " + s)
+	}
+
+	// float64(math.MaxUint64) rounds up to 2^64, which does not fit either.
+	size := f * float64(multiplier)
+	if size >= math.MaxUint64 {
+		return 0, ferrors.NewInvalidArgumentError("byte size is too large: " + s)
+	}
+
+	return ByteSize(size), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = size
+	return nil
+}
+
+// String returns the size with the largest binary unit which divides it,
+// e.g. 10MiB.
+func (b ByteSize) String() string {
+	units := []struct {
+		name string
+		size ByteSize
+	}{
+		{"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+	}
+
+	for _, u := range units {
+		if b >= u.size && b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
@@ -2,125 +2,181 @@
 package fconfig
 
 import (
+	"context"
 	"os"
 	"reflect"
-	"regexp"
+	"sort"
+	"time"
 
 	"github.com/Flahmingo-Investments/helpers-go/ferrors"
-	"github.com/Flahmingo-Investments/helpers-go/gcp"
 	"github.com/joho/godotenv"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
-var secretRegex = regexp.MustCompile(`^gSecret://(?P<Path>.+)`)
+// loadConfig loads the configuration from a given file.
+//
+// It expands the environment variables and secrets, see expander.
+// It merges the overlay of the environment and the included files, see
+// readLayeredConfig.
+// It applies the `default` struct tags, the environment variable and the flag
+// overrides.
+// It validates the configuration using the `validate` and `fconfig` tags.
+func loadConfig(ctx context.Context, file string, config interface{}, opts *options) error {
+	environment := detectEnvironment(opts.lookupEnv)
+	if opts.environment != nil {
+		environment = *opts.environment
+	}
 
-// secretClient is helper to expand gcp.SecretClient to support gSecret in path.
-type secretClient struct {
-	*gcp.SecretClient
-}
+	// Read the configuration with its includes and overlay.
+	lc, err := readLayeredConfig(file, environment)
+	if err != nil {
+		return err
+	}
 
-// getSecret parses a `gSecret://` string into a GCP secret path, and retrieve
-// it from GCP Secret Service.
-func (c *secretClient) getSecret(val string) (string, error) {
-	matches := secretRegex.FindStringSubmatch(val)
-	pathIndex := secretRegex.SubexpIndex("Path")
-	path := matches[pathIndex]
-	return c.GetSecret(path)
+	return decodeConfig(ctx, lc, config, opts)
 }
 
-func decodeEnvVars() mapstructure.DecodeHookFuncType {
-	return func(
-		f reflect.Type,
-		t reflect.Type,
-		data interface{},
-	) (interface{}, error) {
-		// Check that the data is string
-		if f.Kind() != reflect.String {
-			return data, nil
+// decodeConfig decodes the settings into the config, after applying the
+// defaults, overrides and expanding the environment variables and secrets.
+func decodeConfig(ctx context.Context, lc *layeredConfig, config interface{}, opts *options) error {
+	if opts.report != nil {
+		opts.report.Files = lc.files
+	}
+
+	schema := opts.schema
+	if schema == nil && opts.validateSchema {
+		var err error
+		schema, err = Schema(config)
+		if err != nil {
+			return err
 		}
+	}
 
-		// expand environment variables inside config file
-		// e.g. ${ENV_NAME}
-		eval := os.Expand(data.(string), func(str string) string {
-			if str == "$" {
-				return "$"
+	if schema != nil {
+		err := schema.ValidateSettings(lc.settings)
+		if err != nil {
+			return err
+		}
+
+		if opts.strict {
+			if keys := schema.unknownKeys("", lc.settings); len(keys) > 0 {
+				return unknownKeysError(keys)
 			}
+		}
+	}
 
-			return os.Getenv(str)
-		})
+	v := viper.New()
+	err := v.MergeConfigMap(lc.settings)
+	if err != nil {
+		return ferrors.WithStack(err)
+	}
+
+	fields := structFields(reflect.TypeOf(config))
+	applyDefaults(v, fields)
 
-		return eval, nil
+	var envOverrides map[string]string
+	if opts.envOverrides {
+		envOverrides = applyEnvOverrides(v, fields, opts.envPrefix, opts.lookupEnv, opts.resolvers)
 	}
-}
 
-func decodeGSecret(sc *secretClient) mapstructure.DecodeHookFuncType {
-	return func(
-		f reflect.Type,
-		t reflect.Type,
-		data interface{},
-	) (interface{}, error) {
-		// Check that the data is string
-		if f.Kind() != reflect.String {
-			return data, nil
-		}
+	var flagOverrides map[string]bool
+	if opts.flags != nil {
+		flagOverrides = applyFlagOverrides(v, fields, opts.flags, opts.resolvers)
+	}
 
-		if secretRegex.MatchString(data.(string)) {
-			if sc == nil {
-				gsc, err := gcp.NewSecretClient()
-				if err != nil {
-					return "", err
-				}
-				// wrap gsc into secretClient to support `gSecret://` expansion.
-				sc = &secretClient{SecretClient: gsc}
-			}
+	if opts.report != nil {
+		opts.report.Sources = fieldSources(v, fields, envOverrides, flagOverrides)
+	}
 
-			secret, err := sc.getSecret(data.(string))
-			if err != nil {
-				return data, err
-			}
+	// resolvers initialize their clients only when they find a secret to
+	// resolve, so it is cheap to close the ones created by the package.
+	defer opts.closeResolvers()
 
-			return secret, nil
-		}
+	fetcher := newSecretFetcher(opts)
+	exp := &expander{
+		lookupEnv: opts.lookupEnv,
+		resolvers: opts.resolvers,
+		resolve: func(scheme, ref string) (string, error) {
+			return fetcher.resolve(ctx, scheme, ref)
+		},
+	}
 
-		return data, nil
+	// fetch all the secrets upfront, so they are fetched concurrently rather
+	// than one by one while decoding.
+	refs, secretPaths := collectSecretRefs(v.AllSettings(), exp)
+	if opts.report != nil {
+		opts.report.Secrets = secretKeys(refs)
 	}
-}
 
-// loadConfig loads the configuration from a given file.
-//
-// It expands the environment variables if the value matches `${ENV_NAME}`.
-// It fetches the secret if the value matches gSecret://uri.
-func loadConfig(file string, config interface{}) error {
-	// initialize viper and set the config file to read from.
-	v := viper.New()
-	v.SetConfigFile(file)
+	err = fetcher.prefetch(ctx, refs)
+	if err != nil {
+		return err
+	}
+
+	// expand first, so the expanded values are converted to their types.
+	hooks := []mapstructure.DecodeHookFunc{decodeExpand(exp)}
+	hooks = append(hooks, typeDecodeHooks()...)
+	hooks = append(hooks, opts.decodeHooks...)
+
+	var md mapstructure.Metadata
 
-	// Read the configuration.
-	err := v.ReadInConfig()
+	err = v.Unmarshal(config,
+		viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(hooks...)),
+		func(dc *mapstructure.DecoderConfig) { dc.Metadata = &md },
+	)
 	if err != nil {
 		return err
 	}
 
-	// Declaring a client early not initializing it.
-	// So, we can initailize it only when we find a 'gSecret'.
-	var sc *secretClient
+	if opts.report != nil {
+		opts.report.SecretsResolved = fetcher.count()
+	}
+
+	if opts.strict && len(md.Unused) > 0 {
+		return unknownKeysError(md.Unused)
+	}
 
-	defer func() {
-		if sc != nil {
-			_ = sc.Close()
+	if opts.warnUnset {
+		sort.Strings(md.Unset)
+		for _, path := range md.Unset {
+			opts.warn("config field %s is not set by any source", path)
 		}
-	}()
-
-	return v.Unmarshal(config,
-		viper.DecodeHook(
-			mapstructure.ComposeDecodeHookFunc(
-				mapstructure.StringToTimeDurationHookFunc(),
-				mapstructure.StringToSliceHookFunc(","),
-				decodeEnvVars(),
-				decodeGSecret(sc),
-			),
-		))
+	}
+
+	err = Validate(config)
+	if err != nil {
+		return err
+	}
+
+	// remember which values are secrets, so Dump can redact them, and how
+	// the config was loaded for Snapshot.
+	if opts.report != nil {
+		opts.report.load = &loadInfo{
+			secretPaths:    secretPaths,
+			loadedAt:       time.Now(),
+			files:          lc.fileHashes(),
+			envOverrides:   envOverrides,
+			secretVersions: fetcher.versions(),
+		}
+	}
+
+	return nil
+}
+
+// unknownKeysError returns an InvalidArgument error listing the unknown keys.
+func unknownKeysError(keys []string) error {
+	sort.Strings(keys)
+
+	fields := make([]ferrors.Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, ferrors.Field{
+			Name:        key,
+			Description: "unknown configuration key",
+		})
+	}
+
+	return ferrors.NewInvalidArgumentError("configuration has unknown keys", fields...)
 }
 
 // LoadConfig loads the configuration from a given file and unmarshal it into
@@ -135,21 +191,104 @@ func loadConfig(file string, config interface{}) error {
 //	}
 //
 // It expands the environment variables if the value matches `${ENV_NAME}`.
-// It fetches the secret if the value matches gSecret://uri.
-func LoadConfig(file string, config interface{}) error {
-	// load .env file inside the current working directory.
-	err := LoadEnv("")
+// It resolves the secret if the value matches `<scheme>://<ref>`, or embeds it
+// inside a larger string with `${<scheme>://<ref>}`, e.g.
+//
+//	db_uri: "postgres://user:${gSecret://projects/p/secrets/db-pass}@host/db"
+//
+// The values of environment variables are used verbatim, unless the whole
+// value is a secret reference, which is resolved. Use `$$` to write a literal
+// `$` in the config file.
+//
+// The built-in schemes are:
+//   - gSecret://projects/<project>/secrets/<name>/versions/<version> fetches the
+//     secret from GCP Secret Manager.
+//   - enc:age:<base64> decrypts the value encrypted with age, see EncryptValue.
+//     The key is taken from FCONFIG_AGE_KEY or FCONFIG_AGE_KEY_FILE
+//     environment variables, or WithDecryptionKey.
+//
+// The following schemes are resolved only if enabled with
+// WithBuiltinResolvers:
+//   - file:///path/to/secret reads the secret from a file, e.g. Kubernetes
+//     mounted secrets.
+//   - env://ENV_NAME reads the secret from an environment variable.
+//   - vault://<mount>/data/<path>#<key> fetches the secret from HashiCorp Vault.
+//
+// Custom schemes can be registered with WithSecretResolver.
+//
+// If APP_ENV or ENV environment variable is set, the overlay file of the
+// environment is merged on top of the config file, e.g. config.staging.yaml for
+// config.yaml. Files can include other files with the include directive:
+//
+//	include:
+//	  - common.yaml
+//
+// Maps are merged recursively, lists and scalar values are replaced as a
+// whole. See WithEnvironment to select the environment explicitly.
+//
+// Besides the basic types, string values are decoded into time.Duration,
+// time.Time (RFC3339), url.URL, net.IP, net.IPNet, regexp.Regexp, ByteSize
+// (e.g. 10MiB), ferrors.ErrorCode (e.g. NOT_FOUND), []byte (base64) and any
+// type implementing encoding.TextUnmarshaler, e.g. zapcore.Level.
+//
+// Fields without a value in the config file are set to their `default` struct
+// tag, see WithEnvOverrides and WithFlags to override the values with
+// environment variables and command line flags.
+//
+// Once loaded, the configuration is validated using the `validate` and
+// `fconfig` struct tags, see Validate for the supported rules.
+func LoadConfig(file string, config interface{}, opts ...Option) error {
+	return LoadConfigWithOptions(context.Background(), file, config, opts...)
+}
+
+// LoadConfigWithOptions is same as LoadConfig but it accepts a context and
+// options to configure how the configuration is loaded.
+//
+// The context is used to fetch the secrets, loading is aborted once the
+// context is done.
+//
+// Example:
+//
+//	err := fconfig.LoadConfigWithOptions(ctx, "config.yaml", &cfg,
+//		fconfig.WithoutDotEnv(),
+//		fconfig.WithSecretClient(client),
+//		fconfig.WithTimeout(10*time.Second),
+//	)
+func LoadConfigWithOptions(
+	ctx context.Context,
+	file string,
+	config interface{},
+	opts ...Option,
+) error {
+	o := buildOptions(opts...)
+
+	ctx, cancel, err := o.begin(ctx)
 	if err != nil {
-		return ferrors.Wrap(err, "unable to read environment variables")
+		return err
 	}
+	defer cancel()
 
 	// load configuration into the provided config.
-	err = loadConfig(file, config)
-	if err != nil {
-		return err
+	return loadConfig(ctx, file, config, o)
+}
+
+// begin prepares loading a configuration. It applies the timeout to the
+// context and loads the env files.
+func (o *options) begin(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
 	}
 
-	return nil
+	if !o.skipDotEnv {
+		err := o.loadEnv(ctx)
+		if err != nil {
+			cancel()
+			return nil, nil, ferrors.Wrap(err, "unable to read environment variables")
+		}
+	}
+
+	return ctx, cancel, nil
 }
 
 // LoadEnv load environments variables from a file.
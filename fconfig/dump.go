@@ -0,0 +1,201 @@
+package fconfig
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"gopkg.in/yaml.v3"
+)
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// loadInfo is what is known about a loaded config, it is kept in the Report
+// of the load for Dump and Snapshot.
+type loadInfo struct {
+	// secretPaths are the lower cased paths of the values resolved from a
+	// secret resolver.
+	secretPaths map[string]bool
+
+	// loadedAt is the time the config was loaded.
+	loadedAt time.Time
+
+	// files are the files read with the hashes of their content, in the
+	// order they are merged.
+	files []SnapshotFile
+
+	// envOverrides are the names of the environment variables keyed by the
+	// paths they override.
+	envOverrides map[string]string
+
+	// secretVersions are the versions of the resolved secrets keyed by their
+	// reference.
+	secretVersions map[string]string
+}
+
+// Dump returns the config as YAML with the secret values redacted, so it can
+// be logged for debugging.
+//
+// A value is redacted if:
+//   - it was resolved from a secret resolver while loading the config. Pass
+//     the report filled while loading it, see WithReport and
+//     LoadWithMetadata.
+//   - its field is tagged with `secret:"true"`.
+//   - its type is Secret.
+//
+// The report can be nil, then only the last two are redacted.
+// The keys are the `mapstructure` names of the fields.
+//
+// Example:
+//
+//	report := &fconfig.Report{}
+//	err := fconfig.LoadConfig("config.yaml", &cfg, fconfig.WithReport(report))
+//	...
+//	out, err := fconfig.Dump(&cfg, report)
+func Dump(config interface{}, report *Report) ([]byte, error) {
+	tree := redactedTree(config, report)
+
+	out, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to marshal config")
+	}
+
+	return out, nil
+}
+
+// DumpJSON is same as Dump but returns the config as indented JSON.
+func DumpJSON(config interface{}, report *Report) ([]byte, error) {
+	tree := redactedTree(config, report)
+
+	out, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to marshal config")
+	}
+
+	return out, nil
+}
+
+// redactedTree converts the config into maps, slices and scalar values with
+// the secret values redacted.
+func redactedTree(config interface{}, report *Report) interface{} {
+	var secretPaths map[string]bool
+	if report != nil && report.load != nil {
+		secretPaths = report.load.secretPaths
+	}
+
+	d := &dumper{secretPaths: secretPaths}
+	return d.value(reflect.ValueOf(config), "", false)
+}
+
+// dumper converts a config into a tree with the secret values redacted.
+type dumper struct {
+	secretPaths map[string]bool
+}
+
+func (d *dumper) value(v reflect.Value, path string, secret bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if secret || d.secretPaths[strings.ToLower(path)] || v.Type() == secretType {
+		if v.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	if v.CanInterface() {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+
+	if v.Kind() == reflect.Struct && isTextType(v.Type()) {
+		// e.g. url.URL, which is not a TextMarshaler.
+		return textValue(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		d.structValue(v, path, out)
+		return out
+
+	case reflect.Map:
+		out := map[string]interface{}{}
+		for _, key := range v.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			out[name] = d.value(v.MapIndex(key), joinPath(path, name), false)
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = d.value(v.Index(i), path, false)
+		}
+		return out
+	}
+
+	if v.CanInterface() {
+		return v.Interface()
+	}
+
+	return fmt.Sprint(v)
+}
+
+// structValue adds the fields of the struct to out.
+func (d *dumper) structValue(v reflect.Value, path string, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name, squash := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if squash && isNestedStruct(sf.Type) {
+			fv = reflect.Indirect(fv)
+			if fv.IsValid() {
+				d.structValue(fv, path, out)
+			}
+			continue
+		}
+
+		out[name] = d.value(fv, joinPath(path, name), sf.Tag.Get("secret") == "true")
+	}
+}
+
+// joinPath joins the dotted path with the name.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,102 @@
+package fconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dumpConfig struct {
+	Name     string        `mapstructure:"name"`
+	DBUri    string        `mapstructure:"dbUri"`
+	APIKey   string        `mapstructure:"apiKey"`
+	Password string        `mapstructure:"password" secret:"true"`
+	Token    Secret        `mapstructure:"token"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	Nested   struct {
+		Val1 string `mapstructure:"val1"`
+		Val2 int    `mapstructure:"val2"`
+	} `mapstructure:"nested"`
+}
+
+func TestSecret(t *testing.T) {
+	s := Secret("super-secret")
+
+	for _, got := range []string{
+		s.String(),
+		fmt.Sprintf("%v", s),
+		fmt.Sprintf("%+v", struct{ S Secret }{s}),
+		fmt.Sprintf("%#v", s),
+	} {
+		if strings.Contains(got, "super-secret") {
+			t.Errorf("expected secret to be redacted but, got = %s", got)
+		}
+	}
+
+	out, err := json.Marshal(map[string]Secret{"s": s})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if string(out) != `{"s":"[REDACTED]"}` {
+		t.Errorf("expected secret to be redacted but, got = %s", out)
+	}
+
+	if s.Value() != "super-secret" {
+		t.Errorf("expected value = super-secret but, got = %s", s.Value())
+	}
+}
+
+func TestDump(t *testing.T) {
+	resolver := SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "secret-" + ref, nil
+	})
+
+	cfg := &dumpConfig{}
+	report := &Report{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/configDump.yaml", cfg,
+		WithoutDotEnv(),
+		WithSecretResolver("test", resolver),
+		WithReport(report),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	out, err := DumpJSON(cfg, report)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	want := map[string]interface{}{
+		"name":     "service",
+		"dbUri":    redacted,
+		"apiKey":   redacted,
+		"password": redacted,
+		"token":    redacted,
+		"timeout":  "5s",
+		"nested": map[string]interface{}{
+			"val1": redacted,
+			"val2": float64(2),
+		},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected = %v but, got = %v", want, got)
+	}
+
+	yamlOut, err := Dump(cfg, report)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if strings.Contains(string(yamlOut), "secret-") ||
+		strings.Contains(string(yamlOut), "token-value") {
+		t.Errorf("expected secrets to be redacted but, got:\n%s", yamlOut)
+	}
+}
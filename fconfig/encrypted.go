@@ -0,0 +1,149 @@
+package fconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// SchemeEncrypted is the scheme of the values encrypted inside the config
+// file. Unlike the other schemes, they are in the form of
+// `enc:<algorithm>:<data>`. Only the age algorithm is supported, values with
+// other algorithms, e.g. `enc:utf8`, are used as is.
+const SchemeEncrypted = "enc"
+
+// Environment variables holding the age identity used to decrypt the values.
+const (
+	// AgeKeyEnv holds the age secret key, e.g. AGE-SECRET-KEY-1...
+	AgeKeyEnv = "FCONFIG_AGE_KEY"
+
+	// AgeKeyFileEnv holds the path of an age identity file, as generated by
+	// age-keygen.
+	AgeKeyFileEnv = "FCONFIG_AGE_KEY_FILE"
+)
+
+// algorithmAge is the algorithm of the age encrypted values.
+const algorithmAge = "age"
+
+// EncryptValue encrypts the value with age for the recipient, e.g.
+// age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p, and returns
+// it in the form of `enc:age:<base64>` to be put in the config file.
+func EncryptValue(recipient, value string) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", ferrors.Wrap(err, "invalid age recipient")
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, r)
+	if err != nil {
+		return "", ferrors.Wrap(err, "unable to encrypt value")
+	}
+
+	_, err = io.WriteString(w, value)
+	if err != nil {
+		return "", ferrors.Wrap(err, "unable to encrypt value")
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", ferrors.Wrap(err, "unable to encrypt value")
+	}
+
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return SchemeEncrypted + ":" + algorithmAge + ":" + data, nil
+}
+
+// encryptedResolver decrypts the values encrypted inside the config file.
+//
+// The identities are read when the first value is decrypted, so configs
+// without encrypted values do not require a key.
+type encryptedResolver struct {
+	// key is the age secret key, if provided.
+	key string
+
+	// keyFile is the path of the age identity file, if provided.
+	keyFile string
+
+	lookupEnv LookupEnvFunc
+
+	once       sync.Once
+	identities []age.Identity
+	err        error
+}
+
+// loadIdentities reads the age identities from the options or the environment
+// variables.
+func (r *encryptedResolver) loadIdentities() ([]age.Identity, error) {
+	r.once.Do(func() {
+		key, keyFile := r.key, r.keyFile
+		if key == "" && keyFile == "" {
+			key, _ = r.lookupEnv(AgeKeyEnv)
+			keyFile, _ = r.lookupEnv(AgeKeyFileEnv)
+		}
+
+		var src io.Reader
+		switch {
+		case key != "":
+			src = strings.NewReader(key)
+		case keyFile != "":
+			content, err := os.ReadFile(keyFile)
+			if err != nil {
+				r.err = ferrors.Wrapf(err, "unable to read age key file: %s", keyFile)
+				return
+			}
+			src = bytes.NewReader(content)
+		default:
+			r.err = ferrors.NewNotFoundError(
+				"decryption key is not set, set " + AgeKeyEnv + " or " + AgeKeyFileEnv)
+			return
+		}
+
+		identities, err := age.ParseIdentities(src)
+		if err != nil {
+			r.err = ferrors.Wrap(err, "invalid age key")
+			return
+		}
+		r.identities = identities
+	})
+
+	return r.identities, r.err
+}
+
+// Resolve decrypts the `<algorithm>:<data>` ref.
+func (r *encryptedResolver) Resolve(_ context.Context, ref string) (string, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 || parts[0] != algorithmAge {
+		return "", ferrors.NewInvalidArgumentError(
+			"unsupported encryption algorithm, expected enc:age:<data>")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ferrors.Wrap(err, "invalid encrypted value")
+	}
+
+	identities, err := r.loadIdentities()
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return "", ferrors.Wrap(err, "unable to decrypt value")
+	}
+
+	value, err := io.ReadAll(plain)
+	if err != nil {
+		return "", ferrors.Wrap(err, "unable to decrypt value")
+	}
+
+	return string(value), nil
+}
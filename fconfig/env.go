@@ -0,0 +1,210 @@
+package fconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/joho/godotenv"
+)
+
+// dotEnv is the name of the default env file.
+const dotEnv = ".env"
+
+// ReadEnv reads the env files configured by the options and returns their
+// variables without modifying the process environment.
+//
+// Unless WithEnvFiles is provided, it reads .env, .env.local and
+// .env.<environment> in order, if they exist. The environment is taken from
+// WithEnvironment, or APP_ENV or ENV variables which can be set by the
+// previous files as well. Later files override the earlier ones.
+//
+// The variables which are already set in the environment are not returned,
+// unless WithEnvOverload is provided.
+//
+// Example:
+//
+//	vars, err := fconfig.ReadEnv(ctx, fconfig.WithEnvironment("test"))
+func ReadEnv(ctx context.Context, opts ...Option) (map[string]string, error) {
+	o := buildOptions(opts...)
+	defer o.closeResolvers()
+
+	return o.readEnv(ctx)
+}
+
+// LoadEnvFiles is same as ReadEnv but it sets the variables in the process
+// environment.
+func LoadEnvFiles(ctx context.Context, opts ...Option) error {
+	vars, err := ReadEnv(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	return setEnv(vars)
+}
+
+// loadEnv loads the env files into the process environment or, for an
+// isolated environment, into the lookup function of the options.
+func (o *options) loadEnv(ctx context.Context) error {
+	vars, err := o.readEnv(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+
+	if o.isolatedEnv {
+		o.lookupEnv = overlayEnv(vars, o.lookupEnv)
+	} else {
+		err = setEnv(vars)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the env files can configure the resolvers.
+	o.initResolvers()
+
+	return nil
+}
+
+// readEnv reads the env files of the options.
+func (o *options) readEnv(ctx context.Context) (map[string]string, error) {
+	vars := map[string]string{}
+
+	read := func(file string, optional bool) error {
+		fileVars, err := godotenv.Read(file)
+		if err != nil {
+			if optional && os.IsNotExist(err) {
+				return nil
+			}
+			return ferrors.Wrapf(err, "unable to read env file: %s", file)
+		}
+
+		for key, val := range fileVars {
+			vars[key] = val
+		}
+		return nil
+	}
+
+	if len(o.envFiles) > 0 {
+		for _, file := range o.envFiles {
+			err := read(file, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, file := range []string{dotEnv, dotEnv + ".local"} {
+			err := read(filepath.Join(o.envDir, file), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		o.dropSetVars(vars)
+
+		environment := detectEnvironment(overlayEnv(vars, o.lookupEnv))
+		if o.environment != nil {
+			environment = *o.environment
+		}
+
+		if environment != "" {
+			err := read(filepath.Join(o.envDir, dotEnv+"."+environment), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	o.dropSetVars(vars)
+
+	if o.envSecrets {
+		err := o.resolveEnvSecrets(ctx, vars)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return vars, nil
+}
+
+// dropSetVars removes the variables which are already set in the environment,
+// unless the env files overload them.
+func (o *options) dropSetVars(vars map[string]string) {
+	if o.envOverload {
+		return
+	}
+
+	for key := range vars {
+		if _, ok := o.lookupEnv(key); ok {
+			delete(vars, key)
+		}
+	}
+}
+
+// resolveEnvSecrets replaces the values which are secret references with the
+// secrets. The secrets are fetched concurrently.
+func (o *options) resolveEnvSecrets(ctx context.Context, vars map[string]string) error {
+	fetcher := newSecretFetcher(o)
+
+	refs := map[string]secretRef{}
+	for key, val := range vars {
+		if scheme, ref, ok := o.resolvers.parse(val); ok {
+			refs[key] = secretRef{scheme: scheme, ref: ref}
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	pending := make([]secretRef, 0, len(refs))
+	for _, r := range refs {
+		pending = append(pending, r)
+	}
+
+	err := fetcher.prefetch(ctx, pending)
+	if err != nil {
+		return err
+	}
+
+	for key, r := range refs {
+		vars[key], _ = fetcher.lookup(r)
+	}
+
+	return nil
+}
+
+// overlayEnv returns a lookup function which looks up the variables in vars
+// before lookup.
+func overlayEnv(vars map[string]string, lookup LookupEnvFunc) LookupEnvFunc {
+	return func(key string) (string, bool) {
+		if val, ok := vars[key]; ok {
+			return val, true
+		}
+		return lookup(key)
+	}
+}
+
+// setEnv sets the variables in the process environment, in a stable order.
+func setEnv(vars map[string]string) error {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := os.Setenv(key, vars[key])
+		if err != nil {
+			return ferrors.Wrapf(err, "unable to set environment variable: %s", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package fconfig
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+func TestReadEnv(t *testing.T) {
+	secrets := WithLocalSecrets(map[string]string{
+		"projects/p/secrets/env-secret": "secret-value",
+	})
+
+	testCases := []struct {
+		name string
+		env  map[string]string
+		opts []Option
+		want map[string]string
+	}{
+		{
+			name: "default files with the environment from .env",
+			want: map[string]string{
+				"APP_ENV":               "staging",
+				"FCONFIG_TEST_BASE":     "base",
+				"FCONFIG_TEST_OVERRIDE": "staging",
+				"FCONFIG_TEST_LOCAL":    "local",
+				"FCONFIG_TEST_SECRET":   "gSecret://projects/p/secrets/env-secret",
+			},
+		},
+		{
+			name: "environment wins",
+			env:  map[string]string{"APP_ENV": "dev", "FCONFIG_TEST_BASE": "env"},
+			want: map[string]string{
+				"FCONFIG_TEST_OVERRIDE": "local",
+				"FCONFIG_TEST_LOCAL":    "local",
+				"FCONFIG_TEST_SECRET":   "gSecret://projects/p/secrets/env-secret",
+			},
+		},
+		{
+			name: "overload",
+			env:  map[string]string{"FCONFIG_TEST_BASE": "env"},
+			opts: []Option{WithEnvOverload(), WithEnvironment("")},
+			want: map[string]string{
+				"APP_ENV":               "staging",
+				"FCONFIG_TEST_BASE":     "base",
+				"FCONFIG_TEST_OVERRIDE": "local",
+				"FCONFIG_TEST_LOCAL":    "local",
+				"FCONFIG_TEST_SECRET":   "gSecret://projects/p/secrets/env-secret",
+			},
+		},
+		{
+			name: "explicit files with secrets",
+			opts: []Option{
+				WithEnvFiles("testdata/env/.env.staging", "testdata/env/.env"),
+				WithEnvSecrets(),
+				secrets,
+			},
+			want: map[string]string{
+				"APP_ENV":               "staging",
+				"FCONFIG_TEST_BASE":     "base",
+				"FCONFIG_TEST_OVERRIDE": "base",
+				"FCONFIG_TEST_SECRET":   "secret-value",
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			env := tc.env
+			lookup := func(key string) (string, bool) {
+				val, ok := env[key]
+				return val, ok
+			}
+
+			opts := append([]Option{WithEnvDir("testdata/env"), WithLookupEnv(lookup)}, tc.opts...)
+			got, err := ReadEnv(context.Background(), opts...)
+			if err != nil {
+				t.Fatalf("expected error to be nil but, got error: %+v", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected = %v but, got = %v", tc.want, got)
+			}
+		})
+	}
+
+	_, err := ReadEnv(context.Background(), WithEnvFiles("testdata/env/.env.missing"))
+	if err == nil {
+		t.Errorf("expected error for a missing explicit env file but got nil")
+	}
+}
+
+func TestLoadConfigWithIsolatedEnv(t *testing.T) {
+	got := &strictConfig{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/env/config.yaml", got,
+		WithEnvDir("testdata/env"),
+		WithEnvironment("staging"),
+		WithIsolatedEnv(),
+		WithLocalSecrets(map[string]string{
+			"projects/p/secrets/env-secret": "secret-value",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.YamlTestVar != "staging" || got.Nested.Val1 != "secret-value" {
+		t.Errorf("expected values from the env files but, got = %+v", got)
+	}
+
+	if _, ok := os.LookupEnv("FCONFIG_TEST_OVERRIDE"); ok {
+		t.Errorf("expected the process environment not to be modified")
+	}
+}
+
+// closableResolver fails to resolve once it is closed.
+type closableResolver struct {
+	closed bool
+}
+
+func (r *closableResolver) Resolve(_ context.Context, ref string) (string, error) {
+	if r.closed {
+		return "", ferrors.NewInternalError("resolver used after Close")
+	}
+	return "resolved-" + ref, nil
+}
+
+func (r *closableResolver) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestLoadConfigKeepsCustomResolversOpen(t *testing.T) {
+	resolver := &closableResolver{}
+	opts := []Option{
+		WithEnvDir("testdata/env"),
+		WithEnvironment("staging"),
+		WithIsolatedEnv(),
+		WithSecretResolver(SchemeGSecret, resolver),
+	}
+
+	// the resolvers are created again once the env files are loaded, and
+	// closed once the config is loaded.
+	for i := 0; i < 2; i++ {
+		got := &strictConfig{}
+		err := LoadConfigWithOptions(context.Background(), "testdata/env/config.yaml", got, opts...)
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+
+		if want := "resolved-projects/p/secrets/env-secret"; got.Nested.Val1 != want {
+			t.Errorf("expected val1 = %q but, got = %q", want, got.Nested.Val1)
+		}
+	}
+
+	if resolver.closed {
+		t.Errorf("expected the custom resolver not to be closed")
+	}
+}
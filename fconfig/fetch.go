@@ -0,0 +1,322 @@
+package fconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSecretConcurrency is the default maximum number of secrets fetched
+// concurrently.
+const defaultSecretConcurrency = 8
+
+// SecretCache caches resolved secrets, so they are not fetched again when the
+// configuration is reloaded.
+//
+// It is safe for concurrent use. Pass it to WithSecretCache to share it
+// between loads.
+type SecretCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	secret    resolvedSecret
+	expiresAt time.Time
+}
+
+// resolvedSecret is a secret along with its version, if known.
+type resolvedSecret struct {
+	value   string
+	version string
+}
+
+// NewSecretCache creates a new SecretCache whose entries expire after ttl.
+// A zero ttl means the entries never expire.
+func NewSecretCache(ttl time.Duration) *SecretCache {
+	return &SecretCache{
+		ttl:     ttl,
+		entries: map[string]cacheEntry{},
+		now:     time.Now,
+	}
+}
+
+// get returns the cached secret of key if it has not expired.
+func (c *SecretCache) get(key string) (resolvedSecret, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return resolvedSecret{}, false
+	}
+
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return resolvedSecret{}, false
+	}
+
+	return entry.secret, true
+}
+
+// set caches the secret of key.
+func (c *SecretCache) set(key string, secret resolvedSecret) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := cacheEntry{secret: secret}
+	if c.ttl > 0 {
+		entry.expiresAt = c.now().Add(c.ttl)
+	}
+
+	c.entries[key] = entry
+}
+
+// Purge removes all the cached secrets.
+func (c *SecretCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]cacheEntry{}
+}
+
+// secretRef is a reference to a secret.
+type secretRef struct {
+	scheme string
+	ref    string
+}
+
+// key returns the reference as written in the config, i.e.
+// `<scheme>://<ref>` or `enc:<ref>` for the encrypted values.
+func (r secretRef) key() string {
+	if r.scheme == SchemeEncrypted {
+		return r.scheme + ":" + r.ref
+	}
+	return r.scheme + "://" + r.ref
+}
+
+// secretKeys returns the keys of the references, sorted and without
+// duplicates.
+func secretKeys(refs []secretRef) []string {
+	seen := map[string]bool{}
+	keys := make([]string, 0, len(refs))
+	for _, r := range refs {
+		key := r.key()
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// secretFetcher fetches secrets and remembers them for the duration of a load.
+type secretFetcher struct {
+	resolvers   resolvers
+	cache       *SecretCache
+	timeout     time.Duration
+	concurrency int
+
+	mu      sync.Mutex
+	fetched map[string]resolvedSecret
+}
+
+func newSecretFetcher(opts *options) *secretFetcher {
+	concurrency := opts.secretConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultSecretConcurrency
+	}
+
+	return &secretFetcher{
+		resolvers:   opts.resolvers,
+		cache:       opts.secretCache,
+		timeout:     opts.secretTimeout,
+		concurrency: concurrency,
+		fetched:     map[string]resolvedSecret{},
+	}
+}
+
+// lookup returns the secret if it is already fetched or cached.
+func (f *secretFetcher) lookup(r secretRef) (string, bool) {
+	f.mu.Lock()
+	secret, ok := f.fetched[r.key()]
+	f.mu.Unlock()
+	if ok {
+		return secret.value, true
+	}
+
+	if f.cache != nil {
+		if secret, ok := f.cache.get(r.key()); ok {
+			f.store(r, secret)
+			return secret.value, true
+		}
+	}
+
+	return "", false
+}
+
+// store remembers the fetched secret.
+func (f *secretFetcher) store(r secretRef, secret resolvedSecret) {
+	f.mu.Lock()
+	f.fetched[r.key()] = secret
+	f.mu.Unlock()
+
+	if f.cache != nil {
+		f.cache.set(r.key(), secret)
+	}
+}
+
+// fetch resolves the secret with the per call timeout.
+func (f *secretFetcher) fetch(ctx context.Context, r secretRef) (string, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	val, version, err := f.resolvers.resolveVersion(ctx, r.scheme, r.ref)
+	if err != nil {
+		return "", err
+	}
+
+	f.store(r, resolvedSecret{value: val, version: version})
+	return val, nil
+}
+
+// resolve returns the secret, fetching it only if it is not fetched yet.
+func (f *secretFetcher) resolve(ctx context.Context, scheme, ref string) (string, error) {
+	r := secretRef{scheme: scheme, ref: ref}
+	if val, ok := f.lookup(r); ok {
+		return val, nil
+	}
+
+	return f.fetch(ctx, r)
+}
+
+// count returns the number of secrets fetched or found in the cache.
+func (f *secretFetcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.fetched)
+}
+
+// versions returns the versions of the fetched secrets keyed by their
+// reference. Secrets of unknown version have an empty version.
+func (f *secretFetcher) versions() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	versions := make(map[string]string, len(f.fetched))
+	for key, secret := range f.fetched {
+		versions[key] = secret.version
+	}
+
+	return versions
+}
+
+// prefetch fetches the secrets concurrently, at most f.concurrency at a time.
+// Duplicate references are fetched once.
+func (f *secretFetcher) prefetch(ctx context.Context, refs []secretRef) error {
+	pending := map[string]secretRef{}
+	for _, r := range refs {
+		if _, ok := f.lookup(r); !ok {
+			pending[r.key()] = r
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, f.concurrency)
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, r secretRef) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			_, errs[i] = f.fetch(ctx, r)
+		}(i, pending[key])
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingSecret stands for the values of the secrets while collecting the
+// references, so the references depending on them can be told apart.
+const pendingSecret = "\x00pending-secret\x00"
+
+// collectSecretRefs returns the secret references found in the settings,
+// including the ones held by environment variables, and the paths of the
+// values which hold them.
+func collectSecretRefs(
+	settings map[string]interface{},
+	e *expander,
+) ([]secretRef, map[string]bool) {
+	var refs []secretRef
+	paths := map[string]bool{}
+
+	var path string
+
+	collector := *e
+	collector.resolve = func(scheme, ref string) (string, error) {
+		paths[path] = true
+
+		// a reference built from the value of another secret is not known yet,
+		// it is fetched while decoding.
+		if !strings.Contains(ref, pendingSecret) {
+			refs = append(refs, secretRef{scheme: scheme, ref: ref})
+		}
+
+		return pendingSecret, nil
+	}
+
+	var walk func(p string, val interface{})
+	walk = func(p string, val interface{}) {
+		switch v := val.(type) {
+		case string:
+			path = p
+			// errors are reported while decoding.
+			_, _ = collector.expand(v)
+		case map[string]interface{}:
+			for key, item := range v {
+				walk(joinPath(p, key), item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(p, item)
+			}
+		}
+	}
+	walk("", settings)
+
+	return refs, paths
+}
@@ -0,0 +1,159 @@
+package fconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+type fetchConfig struct {
+	First  string   `mapstructure:"first"`
+	Second string   `mapstructure:"second"`
+	Third  string   `mapstructure:"third"`
+	List   []string `mapstructure:"list"`
+}
+
+// countingResolver counts the calls and the maximum concurrent calls.
+type countingResolver struct {
+	delay time.Duration
+
+	mu            sync.Mutex
+	calls         map[string]int
+	active        int32
+	maxConcurrent int32
+}
+
+func (r *countingResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	active := atomic.AddInt32(&r.active, 1)
+	defer atomic.AddInt32(&r.active, -1)
+
+	r.mu.Lock()
+	if r.calls == nil {
+		r.calls = map[string]int{}
+	}
+	r.calls[ref]++
+	if active > r.maxConcurrent {
+		r.maxConcurrent = active
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", ferrors.WithStack(ctx.Err())
+	case <-time.After(r.delay):
+		return "secret-" + ref, nil
+	}
+}
+
+func TestLoadConfigFetchesSecretsOnce(t *testing.T) {
+	resolver := &countingResolver{delay: 20 * time.Millisecond}
+
+	got := &fetchConfig{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/configFetch.yaml", got,
+		WithoutDotEnv(),
+		WithSecretResolver("count", resolver),
+		WithSecretConcurrency(2),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.First != "secret-a" || got.Second != "secret-a" || got.Third != "prefix-secret-b" {
+		t.Errorf("unexpected config: %+v", got)
+	}
+
+	for _, ref := range []string{"a", "b", "c", "d"} {
+		if resolver.calls[ref] != 1 {
+			t.Errorf("expected %s to be fetched once but, fetched %d times", ref, resolver.calls[ref])
+		}
+	}
+
+	if resolver.maxConcurrent != 2 {
+		t.Errorf("expected at most 2 concurrent fetches but, got %d", resolver.maxConcurrent)
+	}
+}
+
+func TestLoadConfigNestedSecretRefs(t *testing.T) {
+	type nestedConfig struct {
+		Nested string `mapstructure:"nested"`
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`nested: "count://outer-${count://inner}"`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := &countingResolver{}
+	report := &Report{}
+
+	got := &nestedConfig{}
+	err = LoadConfigWithOptions(context.Background(), file, got,
+		WithoutDotEnv(),
+		WithSecretResolver("count", resolver),
+		WithReport(report),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.Nested != "secret-outer-secret-inner" {
+		t.Errorf("expected = secret-outer-secret-inner but, got = %s", got.Nested)
+	}
+
+	if len(resolver.calls) != 2 || resolver.calls["outer-"] != 0 {
+		t.Errorf("expected only the inner and the outer secrets to be fetched but, got = %v",
+			resolver.calls)
+	}
+
+	if len(report.Secrets) != 1 || report.Secrets[0] != "count://inner" {
+		t.Errorf("expected secrets = [count://inner] but, got = %v", report.Secrets)
+	}
+}
+
+func TestLoadConfigSecretTimeout(t *testing.T) {
+	err := LoadConfigWithOptions(context.Background(), "testdata/configFetch.yaml", &fetchConfig{},
+		WithoutDotEnv(),
+		WithSecretResolver("count", &countingResolver{delay: time.Second}),
+		WithSecretTimeout(10*time.Millisecond),
+	)
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestLoadConfigSecretCache(t *testing.T) {
+	now := time.Now()
+	cache := NewSecretCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	resolver := &countingResolver{}
+	load := func() {
+		err := LoadConfigWithOptions(context.Background(), "testdata/configFetch.yaml", &fetchConfig{},
+			WithoutDotEnv(),
+			WithSecretResolver("count", resolver),
+			WithSecretCache(cache),
+		)
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+	}
+
+	load()
+	load()
+	if resolver.calls["a"] != 1 {
+		t.Errorf("expected cached secret to be fetched once but, fetched %d times", resolver.calls["a"])
+	}
+
+	now = now.Add(2 * time.Minute)
+	load()
+	if resolver.calls["a"] != 2 {
+		t.Errorf("expected expired secret to be fetched again but, fetched %d times", resolver.calls["a"])
+	}
+}
@@ -0,0 +1,156 @@
+package fconfig
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// structField is a field of a config struct along with its mapstructure path.
+type structField struct {
+	reflect.StructField
+
+	// Path is the dotted mapstructure path of the field, e.g. nested.val1
+	Path string
+
+	// Index is the index sequence to get the field from the root struct with
+	// reflect.Value.FieldByIndex.
+	Index []int
+
+	// Nested is true if the field is a struct whose fields are walked as well.
+	Nested bool
+}
+
+// structFields returns all the fields of a config struct, depth first.
+// Nested structs are returned before their fields.
+//
+// Field names are taken from the `mapstructure` tag, fields tagged with
+// `mapstructure:"-"` are skipped and `mapstructure:",squash"` fields are
+// flattened into their parent.
+func structFields(t reflect.Type) []structField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return appendStructFields(nil, t, "", nil, map[reflect.Type]bool{})
+}
+
+func appendStructFields(
+	fields []structField,
+	t reflect.Type,
+	prefix string,
+	index []int,
+	visiting map[reflect.Type]bool,
+) []structField {
+	// guard against recursive types.
+	if visiting[t] {
+		return fields
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// unexported field.
+			continue
+		}
+
+		name, squash := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		nested := isNestedStruct(sf.Type)
+		if squash && nested {
+			fields = appendStructFields(fields, indirectType(sf.Type), prefix, fieldIndex, visiting)
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fields = append(fields, structField{
+			StructField: sf,
+			Path:        path,
+			Index:       fieldIndex,
+			Nested:      nested,
+		})
+
+		if nested {
+			fields = appendStructFields(fields, indirectType(sf.Type), path, fieldIndex, visiting)
+		}
+	}
+
+	return fields
+}
+
+// fieldName returns the mapstructure name of the field and whether it is
+// squashed into its parent.
+func fieldName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+
+	squash := false
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			squash = true
+		}
+	}
+
+	name := parts[0]
+	if name == "" {
+		name = sf.Name
+	}
+
+	return name, squash
+}
+
+// indirectType returns the type pointed by t, if t is a pointer.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isNestedStruct reports whether t is a struct whose fields are decoded from a
+// nested map, rather than a single value like time.Time or url.URL.
+func isNestedStruct(t reflect.Type) bool {
+	return indirectType(t).Kind() == reflect.Struct && !isTextType(t)
+}
+
+// fieldValue returns the value of the field inside root.
+// It returns false if the field is inside a nil pointer.
+func fieldValue(root reflect.Value, f structField) (reflect.Value, bool) {
+	v := reflect.Indirect(root)
+	for i, x := range f.Index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
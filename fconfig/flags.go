@@ -0,0 +1,121 @@
+package fconfig
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// BindFlags defines a flag for every field of the config struct on the flag
+// set. Pass the flag set to WithFlags once it is parsed, to override the config
+// values with the flags which are set.
+//
+// Flag names are derived from the `mapstructure` path, e.g. db_uri becomes
+// --db-uri, nested.val1 becomes --nested-val1 and envTestVar becomes
+// --env-test-var. The help text is taken from the `desc` tag and the default
+// value shown in the help from the `default` tag.
+//
+// Example:
+//
+//	fs := pflag.NewFlagSet("service", pflag.ExitOnError)
+//	err := fconfig.BindFlags(fs, &cfg)
+//	if err != nil {
+//		return err
+//	}
+//	_ = fs.Parse(os.Args[1:])
+//
+//	err = fconfig.LoadConfig("config.yaml", &cfg, fconfig.WithFlags(fs))
+func BindFlags(fs *pflag.FlagSet, config interface{}) error {
+	t := reflect.TypeOf(config)
+	if t == nil || indirectType(t).Kind() != reflect.Struct {
+		return ferrors.NewInvalidArgumentError("config must be a struct or a pointer to a struct")
+	}
+
+	for _, f := range structFields(t) {
+		if f.Nested {
+			continue
+		}
+
+		name := flagName(f.Path)
+		if fs.Lookup(name) != nil {
+			return ferrors.NewAlreadyExistsError("flag is already defined: "+name, ferrors.Field{
+				Name:        f.Path,
+				Description: "flag --" + name + " is already defined",
+			})
+		}
+
+		desc := f.Tag.Get("desc")
+		def := f.Tag.Get("default")
+
+		if indirectType(f.Type).Kind() == reflect.Bool {
+			// bool flags can be set without a value, e.g. --debug
+			fs.Bool(name, def == "true", desc)
+			continue
+		}
+
+		// values are decoded with the same decode hooks as the config file, so
+		// every flag is a string flag.
+		fs.String(name, def, desc)
+	}
+
+	return nil
+}
+
+// flagName returns the flag name of the dotted path,
+// e.g. nested.dbUri becomes nested-db-uri.
+func flagName(path string) string {
+	var b strings.Builder
+
+	prev := rune(0)
+	for _, r := range path {
+		switch {
+		case r == '.' || r == '_':
+			b.WriteByte('-')
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('-')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+
+	return b.String()
+}
+
+// applyFlagOverrides overrides the values of the fields with the flags which
+// are set. The values are used verbatim, unless they are secret references.
+// It returns the paths which are overridden.
+//
+// The flags are set as overrides rather than bound with viper.BindPFlag, so
+// they take precedence over the environment variable overrides.
+func applyFlagOverrides(
+	v *viper.Viper,
+	fields []structField,
+	fs *pflag.FlagSet,
+	r resolvers,
+) map[string]bool {
+	overridden := map[string]bool{}
+
+	for _, f := range fields {
+		if f.Nested {
+			continue
+		}
+
+		flag := fs.Lookup(flagName(f.Path))
+		if flag == nil || !flag.Changed {
+			continue
+		}
+
+		v.Set(f.Path, verbatim(r, flag.Value.String()))
+		overridden[f.Path] = true
+	}
+
+	return overridden
+}
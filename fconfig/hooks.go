@@ -0,0 +1,154 @@
+package fconfig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/mitchellh/mapstructure"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	urlType       = reflect.TypeOf(url.URL{})
+	regexpType    = reflect.TypeOf(regexp.Regexp{})
+	errorCodeType = reflect.TypeOf(ferrors.ErrorCode(0))
+	bytesType     = reflect.TypeOf([]byte(nil))
+	ipType        = reflect.TypeOf(net.IP(nil))
+	ipNetType     = reflect.TypeOf(net.IPNet{})
+)
+
+// textTypes are the types which typeDecodeHooks decode from a single string,
+// besides the encoding.TextUnmarshaler implementations, see isTextType.
+var textTypes = map[reflect.Type]bool{
+	durationType:  true,
+	timeType:      true,
+	ipType:        true,
+	ipNetType:     true,
+	urlType:       true,
+	regexpType:    true,
+	errorCodeType: true,
+	bytesType:     true,
+}
+
+// isTextType reports whether values of t, or pointers to them, are decoded
+// from a single string, e.g. url.URL, ByteSize or zapcore.Level.
+//
+// Such fields are set with a single flag and described as strings in the
+// schema, even if they are structs or numbers.
+func isTextType(t reflect.Type) bool {
+	t = indirectType(t)
+	return textTypes[t] || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// textValue returns the string form of the value of a text type, e.g. the URL
+// of url.URL whose String method has a pointer receiver.
+func textValue(v reflect.Value) string {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+
+	if s, ok := p.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// typeDecodeHooks returns the decode hooks which convert strings into common
+// types. They run after the environment variables and secrets are expanded.
+func typeDecodeHooks() []mapstructure.DecodeHookFunc {
+	return []mapstructure.DecodeHookFunc{
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+		mapstructure.StringToIPHookFunc(),
+		mapstructure.StringToIPNetHookFunc(),
+		stringToURLHookFunc(),
+		stringToRegexpHookFunc(),
+		stringToErrorCodeHookFunc(),
+		stringToBytesHookFunc(),
+		// zapcore.Level, ByteSize and any other encoding.TextUnmarshaler.
+		mapstructure.TextUnmarshallerHookFunc(),
+		// it splits strings for any slice type, so it must run after the hooks
+		// of slice types e.g. net.IP and []byte.
+		mapstructure.StringToSliceHookFunc(","),
+	}
+}
+
+// stringToURLHookFunc converts strings to url.URL.
+func stringToURLHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != urlType {
+			return data, nil
+		}
+
+		u, err := url.Parse(data.(string))
+		if err != nil {
+			return nil, ferrors.Wrap(err, "invalid url")
+		}
+
+		return u, nil
+	}
+}
+
+// stringToRegexpHookFunc compiles strings to regexp.Regexp.
+func stringToRegexpHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != regexpType {
+			return data, nil
+		}
+
+		re, err := regexp.Compile(data.(string))
+		if err != nil {
+			return nil, ferrors.Wrap(err, "invalid regular expression")
+		}
+
+		return re, nil
+	}
+}
+
+// stringToErrorCodeHookFunc converts error code names to ferrors.ErrorCode.
+// Both NOT_FOUND and NotFound forms are accepted.
+func stringToErrorCodeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != errorCodeType {
+			return data, nil
+		}
+
+		name := data.(string)
+
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(name))); err == nil {
+			return ferrors.ErrorCode(code), nil
+		}
+
+		for c := codes.OK; c <= codes.Unauthenticated; c++ {
+			if c.String() == name {
+				return ferrors.ErrorCode(c), nil
+			}
+		}
+
+		return nil, ferrors.NewInvalidArgumentError("invalid error code: " + name)
+	}
+}
+
+// stringToBytesHookFunc decodes base64 strings to []byte.
+func stringToBytesHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != bytesType {
+			return data, nil
+		}
+
+		b, err := base64.StdEncoding.DecodeString(data.(string))
+		if err != nil {
+			return nil, ferrors.Wrap(err, "invalid base64 value")
+		}
+
+		return b, nil
+	}
+}
@@ -0,0 +1,257 @@
+package fconfig
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+type hooksConfig struct {
+	Timeout     time.Duration     `mapstructure:"timeout"`
+	CreatedAt   time.Time         `mapstructure:"createdAt"`
+	Endpoint    url.URL           `mapstructure:"endpoint"`
+	EndpointPtr *url.URL          `mapstructure:"endpointPtr"`
+	IP          net.IP            `mapstructure:"ip"`
+	Network     net.IPNet         `mapstructure:"network"`
+	Pattern     *regexp.Regexp    `mapstructure:"pattern"`
+	MaxBody     ByteSize          `mapstructure:"maxBody"`
+	CacheSize   ByteSize          `mapstructure:"cacheSize"`
+	LogLevel    zapcore.Level     `mapstructure:"logLevel"`
+	Code        ferrors.ErrorCode `mapstructure:"code"`
+	OtherCode   ferrors.ErrorCode `mapstructure:"otherCode"`
+	Key         []byte            `mapstructure:"key"`
+}
+
+func TestLoadConfigTypeHooks(t *testing.T) {
+	t.Setenv("FCONFIG_TEST_TIMEOUT", "1m30s")
+
+	got := &hooksConfig{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/configHooks.yaml", got,
+		WithoutDotEnv())
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.Timeout != 90*time.Second {
+		t.Errorf("expected timeout = 1m30s but, got = %s", got.Timeout)
+	}
+	if !got.CreatedAt.Equal(time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected createdAt: %s", got.CreatedAt)
+	}
+	if got.Endpoint.Host != "example.com:8443" || got.Endpoint.Query().Get("x") != "1" {
+		t.Errorf("unexpected endpoint: %s", got.Endpoint.String())
+	}
+	if got.EndpointPtr == nil || got.EndpointPtr.String() != "https://example.com" {
+		t.Errorf("unexpected endpointPtr: %v", got.EndpointPtr)
+	}
+	if !got.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("unexpected ip: %s", got.IP)
+	}
+	if got.Network.String() != "10.0.0.0/8" {
+		t.Errorf("unexpected network: %s", got.Network.String())
+	}
+	if got.Pattern == nil || !got.Pattern.MatchString("user-42") {
+		t.Errorf("unexpected pattern: %v", got.Pattern)
+	}
+	if got.MaxBody != 10*MiB {
+		t.Errorf("expected maxBody = 10MiB but, got = %s", got.MaxBody)
+	}
+	if got.CacheSize != 1500*MB {
+		t.Errorf("expected cacheSize = 1.5GB but, got = %d", got.CacheSize)
+	}
+	if got.LogLevel != zapcore.WarnLevel {
+		t.Errorf("expected logLevel = warn but, got = %s", got.LogLevel)
+	}
+	if got.Code != ferrors.NotFound || got.OtherCode != ferrors.PermissionDenied {
+		t.Errorf("unexpected codes: %s, %s", got.Code, got.OtherCode)
+	}
+	if string(got.Key) != "hello" {
+		t.Errorf("expected key = hello but, got = %s", got.Key)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{value: "512", want: 512},
+		{value: "1kb", want: KB},
+		{value: "10MiB", want: 10 * MiB},
+		{value: "2 GiB", want: 2 * GiB},
+		{value: "0.5KiB", want: 512},
+		{value: "10XB", wantErr: true},
+		{value: "MiB", wantErr: true},
+		{value: "16384PiB", wantErr: true},
+		{value: "100000PiB", wantErr: true},
+		{value: "16383.5PiB", want: 16383*PiB + PiB/2},
+		{value: "16384.5PiB", wantErr: true},
+		{value: "18446744073709551615", want: math.MaxUint64},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseByteSize(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if got != tc.want {
+				t.Errorf("expected = %d but, got = %d", tc.want, got)
+			}
+		})
+	}
+
+	if (10 * MiB).String() != "10MiB" {
+		t.Errorf("expected = 10MiB but, got = %s", (10 * MiB).String())
+	}
+}
+
+func TestBindFlagsTypeHooks(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+
+	err := BindFlags(fs, &hooksConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	defined := []string{
+		"endpoint", "endpoint-ptr", "network", "ip", "max-body", "log-level", "code", "key",
+	}
+	for _, name := range defined {
+		if fs.Lookup(name) == nil {
+			t.Errorf("expected flag --%s to be defined", name)
+		}
+	}
+
+	for _, name := range []string{"endpoint-scheme", "endpoint-host", "network-ip", "network-mask"} {
+		if fs.Lookup(name) != nil {
+			t.Errorf("expected flag --%s not to be defined", name)
+		}
+	}
+
+	err = fs.Parse([]string{
+		"--endpoint=https://flag.example.com/v2",
+		"--network=192.168.0.0/16",
+		"--max-body=1MiB",
+	})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	t.Setenv("FCONFIG_TEST_TIMEOUT", "1m30s")
+
+	got := &hooksConfig{}
+	err = LoadConfigWithOptions(context.Background(), "testdata/configHooks.yaml", got,
+		WithoutDotEnv(),
+		WithFlags(fs),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.Endpoint.String() != "https://flag.example.com/v2" {
+		t.Errorf("expected endpoint = https://flag.example.com/v2 but, got = %s", got.Endpoint.String())
+	}
+	if got.Network.String() != "192.168.0.0/16" {
+		t.Errorf("expected network = 192.168.0.0/16 but, got = %s", got.Network.String())
+	}
+	if got.MaxBody != MiB {
+		t.Errorf("expected maxBody = 1MiB but, got = %s", got.MaxBody)
+	}
+}
+
+func TestSchemaTypeHooks(t *testing.T) {
+	schema, err := Schema(&hooksConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	for name, prop := range schema.Properties {
+		if prop.Type != "string" && !reflect.DeepEqual(prop.Coerced, []string{"string"}) {
+			t.Errorf("expected %s to accept strings but, got = %s %v", name, prop.Type, prop.Coerced)
+		}
+	}
+
+	err = ValidateFile("testdata/configHooks.yaml", &hooksConfig{})
+	if err != nil {
+		t.Errorf("expected error to be nil but, got error: %+v", err)
+	}
+}
+
+func TestValidateTypeHooks(t *testing.T) {
+	type config struct {
+		Endpoint    url.URL       `mapstructure:"endpoint" validate:"url"`
+		EndpointPtr *url.URL      `mapstructure:"endpointPtr" validate:"required,url"`
+		LogLevel    zapcore.Level `mapstructure:"logLevel" validate:"oneof=warn error"`
+	}
+
+	valid, _ := url.Parse("https://example.com/api")
+	relative, _ := url.Parse("/api")
+
+	testCases := []struct {
+		name   string
+		config config
+		fields []string
+	}{
+		{
+			name:   "should accept valid values",
+			config: config{Endpoint: *valid, EndpointPtr: valid, LogLevel: zapcore.WarnLevel},
+		},
+		{
+			name:   "should reject invalid values",
+			config: config{Endpoint: *relative, EndpointPtr: relative, LogLevel: zapcore.InfoLevel},
+			fields: []string{"endpoint", "endpointPtr", "logLevel"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(&tc.config)
+			if len(tc.fields) == 0 {
+				if err != nil {
+					t.Errorf("expected error to be nil but, got error: %+v", err)
+				}
+				return
+			}
+
+			if ferrors.Code(err) != ferrors.InvalidArgument {
+				t.Fatalf("expected InvalidArgument error but, got = %v", err)
+			}
+
+			st, _ := status.FromError(err)
+			var got []string
+			for _, detail := range st.Details() {
+				if br, ok := detail.(*errdetails.BadRequest); ok {
+					for _, v := range br.FieldViolations {
+						got = append(got, v.Field)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tc.fields) {
+				t.Errorf("expected fields = %v but, got = %v", tc.fields, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package fconfig
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// maxExpandDepth is the maximum depth of nested expansion.
+const maxExpandDepth = 32
+
+// expander expands environment variables and secret references in strings.
+//
+// The supported syntax is:
+//   - <scheme>://<ref> as a whole value resolves the secret.
+//   - ${<scheme>://<ref>} resolves the secret and embeds it in the string.
+//   - ${ENV_NAME} or $ENV_NAME expands the environment variable. Its value is
+//     used verbatim, unless the whole value is a secret reference, which is
+//     resolved.
+//   - $$ is an escaped `$`.
+//
+// References can be nested, e.g. ${gSecret://projects/${PROJECT}/secrets/db}.
+// Resolved secret values are never expanded.
+type expander struct {
+	lookupEnv LookupEnvFunc
+
+	// resolvers are used to recognize the secret references.
+	resolvers resolvers
+
+	// resolve resolves a secret reference.
+	resolve func(scheme, ref string) (string, error)
+}
+
+// expand expands the environment variables and secrets in s.
+func (e *expander) expand(s string) (string, error) {
+	return e.expandValue(s, nil)
+}
+
+// expandValue expands s. envStack holds the environment variables being
+// expanded, to detect cycles.
+func (e *expander) expandValue(s string, envStack []string) (string, error) {
+	if len(envStack) > maxExpandDepth {
+		return "", ferrors.Newf("too many nested expansions: %s", strings.Join(envStack, " -> "))
+	}
+
+	if scheme, ref, ok := e.resolvers.parse(s); ok {
+		ref, err := e.interpolate(ref, envStack)
+		if err != nil {
+			return "", err
+		}
+
+		return e.resolve(scheme, ref)
+	}
+
+	return e.interpolate(s, envStack)
+}
+
+// interpolate expands the `${...}`, `$NAME` and `$$` occurrences in s.
+func (e *expander) interpolate(s string, envStack []string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var buf strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case next == '$':
+			buf.WriteByte('$')
+			i++
+
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", ferrors.Newf("unterminated ${ in value: %s", s)
+			}
+
+			val, err := e.expandReference(s[i+2:end], envStack)
+			if err != nil {
+				return "", err
+			}
+
+			buf.WriteString(val)
+			i = end
+
+		case isNameStart(next):
+			end := i + 2
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+
+			val, err := e.expandEnv(s[i+1:end], envStack)
+			if err != nil {
+				return "", err
+			}
+
+			buf.WriteString(val)
+			i = end - 1
+
+		default:
+			buf.WriteByte('$')
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// expandReference expands the content of `${...}`, which is either a secret
+// reference or an environment variable name.
+func (e *expander) expandReference(content string, envStack []string) (string, error) {
+	content, err := e.interpolate(content, envStack)
+	if err != nil {
+		return "", err
+	}
+
+	if scheme, ref, ok := e.resolvers.parse(content); ok {
+		return e.resolve(scheme, ref)
+	}
+
+	return e.expandEnv(content, envStack)
+}
+
+// expandEnv returns the expanded value of the environment variable.
+// Missing environment variables are expanded to empty string.
+func (e *expander) expandEnv(name string, envStack []string) (string, error) {
+	for _, n := range envStack {
+		if n == name {
+			return "", ferrors.Newf(
+				"cycle detected while expanding environment variables: %s -> %s",
+				strings.Join(envStack, " -> "), name,
+			)
+		}
+	}
+
+	val, _ := e.lookupEnv(name)
+
+	// the value is used verbatim, so passwords and tokens containing `$` are
+	// kept as is. Only a value that is a secret reference is expanded.
+	if _, _, ok := e.resolvers.parse(val); !ok {
+		return val, nil
+	}
+
+	return e.expandValue(val, append(envStack[:len(envStack):len(envStack)], name))
+}
+
+// verbatim escapes the `$` of a value taken from the environment or a flag, so
+// it is used as is while decoding. A value that is a secret reference is kept, so it
+// is resolved, same as the environment variables expanded by expandEnv.
+func verbatim(r resolvers, val string) string {
+	if _, _, ok := r.parse(val); ok {
+		return val
+	}
+
+	return strings.ReplaceAll(val, "$", "$$")
+}
+
+// matchingBrace returns the index of the `}` matching the `{` at open, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9')
+}
+
+// decodeExpand expands the environment variables and secrets in string values.
+func decodeExpand(e *expander) mapstructure.DecodeHookFuncType {
+	return func(
+		f reflect.Type,
+		t reflect.Type,
+		data interface{},
+	) (interface{}, error) {
+		// Check that the data is string
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+
+		return e.expand(data.(string))
+	}
+}
@@ -0,0 +1,97 @@
+package fconfig
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// applyDefaults sets the values of the `default` struct tags as viper
+// defaults, so they have the lowest precedence.
+//
+// Example:
+//
+//	type Config struct {
+//		Port int `mapstructure:"port" default:"8080"`
+//	}
+func applyDefaults(v *viper.Viper, fields []structField) {
+	for _, f := range fields {
+		if f.Nested {
+			continue
+		}
+
+		if val, ok := f.Tag.Lookup("default"); ok {
+			v.SetDefault(f.Path, val)
+		}
+	}
+}
+
+// envName returns the environment variable name for the dotted path,
+// e.g. nested.val1 becomes APP_NESTED_VAL1 with APP prefix.
+func envName(prefix, path string) string {
+	name := strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+	if prefix == "" {
+		return name
+	}
+
+	return strings.ToUpper(prefix) + "_" + name
+}
+
+// applyEnvOverrides overrides the values of the fields with the environment
+// variables derived from their path. The values are used verbatim, unless they
+// are secret references.
+// It returns the names of the variables keyed by the paths they override.
+func applyEnvOverrides(
+	v *viper.Viper,
+	fields []structField,
+	prefix string,
+	lookupEnv LookupEnvFunc,
+	r resolvers,
+) map[string]string {
+	overridden := map[string]string{}
+
+	for _, f := range fields {
+		if f.Nested {
+			continue
+		}
+
+		name := envName(prefix, f.Path)
+		if val, ok := lookupEnv(name); ok {
+			v.Set(f.Path, verbatim(r, val))
+			overridden[f.Path] = name
+		}
+	}
+
+	return overridden
+}
+
+// fieldSources returns where the final value of each field came from.
+func fieldSources(
+	v *viper.Viper,
+	fields []structField,
+	envOverrides map[string]string,
+	flagOverrides map[string]bool,
+) map[string]ValueSource {
+	sources := map[string]ValueSource{}
+
+	for _, f := range fields {
+		if f.Nested {
+			continue
+		}
+
+		_, hasDefault := f.Tag.Lookup("default")
+
+		switch {
+		case flagOverrides[f.Path]:
+			sources[f.Path] = SourceFlag
+		case envOverrides[f.Path] != "":
+			sources[f.Path] = SourceEnv
+		case v.InConfig(f.Path):
+			sources[f.Path] = SourceFile
+		case hasDefault:
+			sources[f.Path] = SourceDefault
+		}
+	}
+
+	return sources
+}
@@ -0,0 +1,129 @@
+package fconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type layersNested struct {
+	Val1 string `mapstructure:"val1" default:"from-default"`
+	Val2 int    `mapstructure:"val2" default:"2"`
+}
+
+type layersConfig struct {
+	Name    string        `mapstructure:"name" default:"from-default"`
+	Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+	Hosts   []string      `mapstructure:"hosts" default:"a,b"`
+	Unset   string        `mapstructure:"unset"`
+	Nested  layersNested  `mapstructure:"nested"`
+}
+
+func TestLoadConfigLayers(t *testing.T) {
+	env := map[string]string{
+		"APP_NESTED_VAL1": "from-env",
+		"APP_TIMEOUT":     "10s",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	testCases := []struct {
+		name        string
+		opts        []Option
+		want        *layersConfig
+		wantSources map[string]ValueSource
+	}{
+		{
+			name: "should apply defaults below the config file",
+			opts: []Option{WithoutDotEnv(), WithLookupEnv(lookupEnv)},
+			want: &layersConfig{
+				Name:    "from-file",
+				Timeout: 5 * time.Second,
+				Hosts:   []string{"a", "b"},
+				Nested:  layersNested{Val1: "from-file", Val2: 2},
+			},
+			wantSources: map[string]ValueSource{
+				"name":        SourceFile,
+				"timeout":     SourceDefault,
+				"hosts":       SourceDefault,
+				"nested.val1": SourceFile,
+				"nested.val2": SourceDefault,
+			},
+		},
+		{
+			name: "should apply env overrides above the config file",
+			opts: []Option{WithoutDotEnv(), WithLookupEnv(lookupEnv), WithEnvOverrides("app")},
+			want: &layersConfig{
+				Name:    "from-file",
+				Timeout: 10 * time.Second,
+				Hosts:   []string{"a", "b"},
+				Nested:  layersNested{Val1: "from-env", Val2: 2},
+			},
+			wantSources: map[string]ValueSource{
+				"name":        SourceFile,
+				"timeout":     SourceEnv,
+				"hosts":       SourceDefault,
+				"nested.val1": SourceEnv,
+				"nested.val2": SourceDefault,
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			got := &layersConfig{}
+			report := &Report{}
+
+			opts := append(tc.opts, WithReport(report))
+			err := LoadConfigWithOptions(context.Background(), "testdata/configLayers.yaml", got, opts...)
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected = %+v but, got = %+v", tc.want, got)
+			}
+
+			if !reflect.DeepEqual(report.Sources, tc.wantSources) {
+				t.Errorf("expected sources = %+v but, got = %+v", tc.wantSources, report.Sources)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesVerbatim(t *testing.T) {
+	env := map[string]string{
+		"APP_NAME":        "p$$w$ord",
+		"APP_NESTED_VAL1": "test://val1",
+	}
+
+	got := &layersConfig{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/configLayers.yaml", got,
+		WithoutDotEnv(),
+		WithEnvOverrides("app"),
+		WithLookupEnv(func(key string) (string, bool) {
+			val, ok := env[key]
+			return val, ok
+		}),
+		WithSecretResolver("test", SecretResolverFunc(
+			func(_ context.Context, ref string) (string, error) {
+				return "resolved-" + ref, nil
+			},
+		)),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if got.Name != "p$$w$ord" {
+		t.Errorf("expected name = p$$w$ord but, got = %s", got.Name)
+	}
+	if got.Nested.Val1 != "resolved-val1" {
+		t.Errorf("expected nested.val1 = resolved-val1 but, got = %s", got.Nested.Val1)
+	}
+}
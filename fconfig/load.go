@@ -0,0 +1,74 @@
+package fconfig
+
+import (
+	"context"
+	"reflect"
+)
+
+// Metadata describes how a configuration was loaded by LoadWithMetadata.
+type Metadata struct {
+	// File is the config file passed to LoadWithMetadata.
+	File string
+
+	// Report holds the files read, the source of each value, the secrets
+	// referenced and resolved, and the warnings.
+	Report
+}
+
+// Load loads the configuration from the file into a new T.
+// It is same as LoadConfig but returns a typed value.
+//
+// T is usually a struct, a pointer to a struct is allocated as well.
+// Use LoadWithMetadata to Dump the config with the values resolved from
+// secrets redacted.
+//
+// Example:
+//
+//	cfg, err := fconfig.Load[Config]("config.yaml", fconfig.WithStrict())
+func Load[T any](file string, opts ...Option) (T, error) {
+	config, _, err := LoadWithMetadata[T](file, opts...)
+	return config, err
+}
+
+// MustLoad is same as Load but panics if the configuration cannot be loaded.
+// It is meant to be used in main, where there is nothing to do without a
+// configuration.
+func MustLoad[T any](file string, opts ...Option) T {
+	config, err := Load[T](file, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return config
+}
+
+// LoadWithMetadata is same as Load but it returns the metadata of the load as
+// well. It replaces the report configured with WithReport, if any.
+func LoadWithMetadata[T any](file string, opts ...Option) (T, *Metadata, error) {
+	md := &Metadata{File: file}
+	opts = append(opts, WithReport(&md.Report))
+
+	config, target := newConfig[T]()
+	err := LoadConfigWithOptions(context.Background(), file, target, opts...)
+	if err != nil {
+		var zero T
+		return zero, nil, err
+	}
+
+	return *config, md, nil
+}
+
+// newConfig allocates a new T and returns the target to decode it into.
+// If T is a pointer, the value it points to is allocated as well and it is
+// the target.
+func newConfig[T any]() (*T, interface{}) {
+	config := new(T)
+
+	rv := reflect.ValueOf(config).Elem()
+	if rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		return config, rv.Interface()
+	}
+
+	return config, config
+}
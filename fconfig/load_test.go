@@ -0,0 +1,77 @@
+package fconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	resolver := SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "secret-" + ref, nil
+	})
+	opts := []Option{WithoutDotEnv(), WithSecretResolver("test", resolver)}
+
+	t.Run("struct", func(t *testing.T) {
+		cfg, md, err := LoadWithMetadata[dumpConfig]("testdata/configDump.yaml", opts...)
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+
+		if cfg.APIKey != "secret-api-key" {
+			t.Errorf("expected apiKey = secret-api-key but, got = %s", cfg.APIKey)
+		}
+
+		if md.File != "testdata/configDump.yaml" {
+			t.Errorf("expected file = testdata/configDump.yaml but, got = %s", md.File)
+		}
+
+		// db-pass, api-key and nested.
+		if md.SecretsResolved != 3 {
+			t.Errorf("expected 3 secrets resolved but, got = %d", md.SecretsResolved)
+		}
+
+		if len(md.Files) != 1 {
+			t.Errorf("expected 1 file read but, got = %v", md.Files)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		cfg, md, err := LoadWithMetadata[*dumpConfig]("testdata/configDump.yaml", opts...)
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+
+		if cfg == nil || cfg.Nested.Val1 != "secret-nested" {
+			t.Fatalf("expected nested.val1 = secret-nested but, got = %+v", cfg)
+		}
+
+		out, err := Dump(cfg, &md.Report)
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+		if strings.Contains(string(out), "secret-") {
+			t.Errorf("expected secrets to be redacted but, got:\n%s", out)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		cfg, err := Load[*dumpConfig]("testdata/missing.yaml", opts...)
+		if err == nil {
+			t.Errorf("expected error but got nil")
+		}
+		if cfg != nil {
+			t.Errorf("expected config to be nil but, got = %+v", cfg)
+		}
+	})
+
+	t.Run("must load panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected MustLoad to panic")
+			}
+		}()
+
+		MustLoad[dumpConfig]("testdata/missing.yaml", opts...)
+	})
+}
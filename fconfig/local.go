@@ -0,0 +1,110 @@
+package fconfig
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"gopkg.in/yaml.v3"
+)
+
+// LocalSecretsEnv is the environment variable holding the path of a local
+// secrets file. If it is set, `gSecret://` secrets are resolved from the file
+// instead of GCP Secret Manager.
+const LocalSecretsEnv = "FCONFIG_LOCAL_SECRETS"
+
+// latestVersion is the suffix of the latest version of a secret.
+const latestVersion = "/versions/latest"
+
+// LocalSecretResolver resolves `gSecret://` secrets from memory, without
+// network. It is useful for hermetic tests and offline development.
+//
+// Secrets are keyed by their path, e.g.
+// projects/my-project/secrets/db-pass/versions/latest. A path without version
+// matches the latest version and vice versa.
+type LocalSecretResolver struct {
+	// file to load the secrets from, on first use.
+	file string
+	once sync.Once
+	err  error
+
+	secrets map[string]string
+}
+
+// NewLocalSecretResolver creates a LocalSecretResolver holding the secrets
+// keyed by their path.
+func NewLocalSecretResolver(secrets map[string]string) *LocalSecretResolver {
+	r := &LocalSecretResolver{secrets: map[string]string{}}
+	for path, val := range secrets {
+		r.secrets[normalizeSecretPath(path)] = val
+	}
+
+	return r
+}
+
+// NewLocalSecretResolverFromFile creates a LocalSecretResolver holding the
+// secrets of a YAML or JSON file, which maps the secret paths to their values:
+//
+//	projects/my-project/secrets/db-pass/versions/latest: "local-password"
+//	projects/my-project/secrets/api-key: "local-api-key"
+//
+// The file is read when the first secret is resolved.
+func NewLocalSecretResolverFromFile(file string) *LocalSecretResolver {
+	return &LocalSecretResolver{file: file}
+}
+
+// load reads the secrets file, only once.
+func (r *LocalSecretResolver) load() error {
+	if r.file == "" {
+		return nil
+	}
+
+	r.once.Do(func() {
+		content, err := os.ReadFile(r.file)
+		if err != nil {
+			r.err = ferrors.Wrapf(err, "unable to read local secrets file: %s", r.file)
+			return
+		}
+
+		var secrets map[string]string
+		err = yaml.Unmarshal(content, &secrets)
+		if err != nil {
+			r.err = ferrors.Wrapf(err, "unable to parse local secrets file: %s", r.file)
+			return
+		}
+
+		r.secrets = map[string]string{}
+		for path, val := range secrets {
+			r.secrets[normalizeSecretPath(path)] = val
+		}
+	})
+
+	return r.err
+}
+
+// Resolve returns the secret of the path.
+func (r *LocalSecretResolver) Resolve(_ context.Context, ref string) (string, error) {
+	err := r.load()
+	if err != nil {
+		return "", err
+	}
+
+	val, ok := r.secrets[normalizeSecretPath(ref)]
+	if !ok {
+		return "", ferrors.NewNotFoundError("local secret not found: " + ref)
+	}
+
+	return val, nil
+}
+
+// normalizeSecretPath appends the latest version to the path if it has no
+// version.
+func normalizeSecretPath(path string) string {
+	path = strings.Trim(path, "/")
+	if !strings.Contains(path, "/versions/") {
+		return path + latestVersion
+	}
+	return path
+}
@@ -0,0 +1,106 @@
+package fconfig
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// localConfig is same as the config of the integration tests.
+type localConfig struct {
+	EnvTestVar       string `mapstructure:"envTestVar"`
+	YamlTestVar      string `mapstructure:"yamlTestVar"`
+	SecretTestVar    string `mapstructure:"secretTestVar"`
+	SecretEnvTestVar string `mapstructure:"secretEnvTestVar"`
+	Nested           struct {
+		Val1 string `mapstructure:"val1"`
+		Val2 int    `mapstructure:"val2"`
+		Val3 bool   `mapstructure:"val3"`
+	} `mapstructure:"nested"`
+}
+
+func TestLoadConfigWithLocalSecrets(t *testing.T) {
+	t.Cleanup(func() {
+		// test.env is loaded into the process environment.
+		_ = os.Unsetenv("ENV_TEST_VAR")
+		_ = os.Unsetenv("SECRET_ENV_TEST_VAR")
+	})
+
+	want := &localConfig{
+		EnvTestVar:       "EnvVarValue 1235543",
+		YamlTestVar:      "Yaml Test",
+		SecretTestVar:    "test-value",
+		SecretEnvTestVar: "test-value",
+	}
+	want.Nested.Val1 = "test"
+	want.Nested.Val2 = 2
+	want.Nested.Val3 = true
+
+	testCases := []struct {
+		name string
+		opts []Option
+		env  map[string]string
+	}{
+		{
+			name: "should resolve secrets from a local file",
+			opts: []Option{WithLocalSecretsFile("testdata/secrets.yaml")},
+		},
+		{
+			name: "should resolve secrets from a map",
+			opts: []Option{WithLocalSecrets(map[string]string{
+				"projects/development-flahmingo/secrets/test-secret": "test-value",
+			})},
+		},
+		{
+			name: "should resolve secrets from a file set by env var",
+			env:  map[string]string{LocalSecretsEnv: "testdata/secrets.yaml"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			for key, val := range tc.env {
+				t.Setenv(key, val)
+			}
+
+			got := &localConfig{}
+			opts := append([]Option{WithEnvFiles("testdata/test.env")}, tc.opts...)
+
+			err := LoadConfigWithOptions(context.Background(), "testdata/config.yaml", got, opts...)
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected = %+v but, got = %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestLocalSecretResolver(t *testing.T) {
+	r := NewLocalSecretResolver(map[string]string{
+		"projects/p/secrets/versioned/versions/2": "v2",
+	})
+
+	got, err := r.Resolve(context.Background(), "projects/p/secrets/versioned/versions/2")
+	if err != nil || got != "v2" {
+		t.Errorf("expected = v2 but, got = %s, error: %+v", got, err)
+	}
+
+	_, err = r.Resolve(context.Background(), "projects/p/secrets/versioned")
+	if ferrors.Code(err) != ferrors.NotFound {
+		t.Errorf("expected error code = %s but, got = %s", ferrors.NotFound, ferrors.Code(err))
+	}
+
+	missing := NewLocalSecretResolverFromFile("testdata/missing.yaml")
+	_, err = missing.Resolve(context.Background(), "projects/p/secrets/s")
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
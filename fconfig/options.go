@@ -0,0 +1,442 @@
+package fconfig
+
+import (
+	"os"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/gcp"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"google.golang.org/api/option"
+)
+
+// LookupEnvFunc retrieves the value of the environment variable named by the
+// key. It reports whether the variable is present, same as os.LookupEnv.
+type LookupEnvFunc func(key string) (string, bool)
+
+// options configures how a configuration is loaded.
+// NOTE: Don't use it directly.
+type options struct {
+	// envFiles are the env files to load before loading the configuration.
+	// Defaults to .env inside the current working directory.
+	envFiles []string
+
+	// skipDotEnv disables loading of env files.
+	skipDotEnv bool
+
+	// envDir is the directory of the default env files.
+	// Defaults to the current working directory.
+	envDir string
+
+	// envOverload makes the env files override the variables already set in
+	// the environment.
+	envOverload bool
+
+	// envSecrets resolves the secret references inside the env files before
+	// setting them.
+	envSecrets bool
+
+	// isolatedEnv keeps the variables of the env files in the options rather
+	// than setting them in the process environment.
+	isolatedEnv bool
+
+	// secretClient is used to fetch `gSecret://` secrets instead of creating
+	// a new client. It is owned by the caller and is not closed.
+	secretClient *gcp.SecretClient
+
+	// clientOpts are used to create the GCP secret client.
+	clientOpts []option.ClientOption
+
+	// timeout is the maximum duration to load the configuration including
+	// fetching the secrets. Zero means no timeout.
+	timeout time.Duration
+
+	// secretTimeout is the maximum duration to fetch a single secret.
+	// Zero means no timeout.
+	secretTimeout time.Duration
+
+	// secretConcurrency is the maximum number of secrets fetched
+	// concurrently.
+	secretConcurrency int
+
+	// secretCache caches the secrets between loads, if provided.
+	secretCache *SecretCache
+
+	// lookupEnv is used to expand the environment variables.
+	lookupEnv LookupEnvFunc
+
+	// decodeHooks are run after the built-in decode hooks.
+	decodeHooks []mapstructure.DecodeHookFunc
+
+	// envOverrides enables overriding the config values with environment
+	// variables derived from their paths.
+	envOverrides bool
+
+	// envPrefix is the prefix of the environment variables used to override
+	// the config values.
+	envPrefix string
+
+	// strict fails loading if the config file has keys which do not map to
+	// any config field.
+	strict bool
+
+	// validateSchema validates the config files against the JSON Schema of
+	// the config struct before decoding.
+	validateSchema bool
+
+	// schema validates the config files before decoding instead of the
+	// schema of the config struct, if provided.
+	schema *JSONSchema
+
+	// warnUnset warns about the config fields which are not set by any
+	// source.
+	warnUnset bool
+
+	// environment selects the overlay config file.
+	// Defaults to APP_ENV or ENV environment variable.
+	environment *string
+
+	// flags override the config values, if they are set.
+	flags *pflag.FlagSet
+
+	// report is filled while loading the configuration, if provided.
+	report *Report
+
+	// localSecrets resolves `gSecret://` secrets instead of GCP Secret
+	// Manager, if provided.
+	localSecrets *LocalSecretResolver
+
+	// decryptionKey is the age secret key used to decrypt the encrypted
+	// values.
+	decryptionKey string
+
+	// decryptionKeyFile is the path of the age identity file used to decrypt
+	// the encrypted values.
+	decryptionKeyFile string
+
+	// builtinResolvers are the schemes of the opt-in built-in resolvers.
+	builtinResolvers []string
+
+	// customResolvers are the secret resolvers registered by the user, keyed
+	// by their URI scheme.
+	customResolvers resolvers
+
+	// resolvers are the secret resolvers keyed by their URI scheme.
+	resolvers resolvers
+
+	// ownResolvers are the resolvers created by the package, which are closed
+	// once they are no longer used. The custom resolvers are owned by the
+	// caller, the same as the client of WithSecretClient.
+	ownResolvers resolvers
+}
+
+// Option configures how a configuration is loaded.
+type Option func(*options)
+
+func buildOptions(opts ...Option) *options {
+	o := &options{
+		lookupEnv:       os.LookupEnv,
+		customResolvers: resolvers{},
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	o.initResolvers()
+
+	return o
+}
+
+// initResolvers creates the secret resolvers, closing the previous ones
+// created by the package. It is called again once the env files are loaded,
+// since they can configure the resolvers, e.g. FCONFIG_LOCAL_SECRETS.
+func (o *options) initResolvers() {
+	o.closeResolvers()
+	o.ownResolvers = defaultResolvers(o)
+
+	if o.localSecrets == nil {
+		if file, ok := o.lookupEnv(LocalSecretsEnv); ok && file != "" {
+			o.ownResolvers[SchemeGSecret] = NewLocalSecretResolverFromFile(file)
+		}
+	}
+
+	o.resolvers = resolvers{}
+	for scheme, resolver := range o.ownResolvers {
+		o.resolvers[scheme] = resolver
+	}
+	for scheme, resolver := range o.customResolvers {
+		o.resolvers[scheme] = resolver
+	}
+}
+
+// closeResolvers closes the resolvers created by the package. The custom
+// resolvers are left open, since they can be used by other loads.
+func (o *options) closeResolvers() {
+	o.ownResolvers.close()
+	o.ownResolvers = nil
+}
+
+// WithSecretResolver registers a secret resolver for the given URI scheme.
+// Values matching `<scheme>://<ref>` are resolved using it.
+//
+// It replaces the built-in resolver if the scheme is already registered.
+// The resolver is not closed by the package, even if it implements io.Closer.
+func WithSecretResolver(scheme string, resolver SecretResolver) Option {
+	return func(o *options) {
+		o.customResolvers[scheme] = resolver
+	}
+}
+
+// WithBuiltinResolvers enables the built-in resolvers of the given schemes,
+// i.e. SchemeFile, SchemeEnv or SchemeVault. Other schemes are ignored.
+//
+// Only `gSecret://` references are resolved by default, so existing values
+// such as `file://migrations` are kept as is.
+func WithBuiltinResolvers(schemes ...string) Option {
+	return func(o *options) {
+		o.builtinResolvers = append(o.builtinResolvers, schemes...)
+	}
+}
+
+// WithEnvFiles loads the given env files, in order, instead of the default
+// .env, .env.local and .env.<environment> files. Later files override the
+// earlier ones.
+// Unlike the default files, it is an error if any of the files does not
+// exist.
+func WithEnvFiles(files ...string) Option {
+	return func(o *options) {
+		o.envFiles = append(o.envFiles, files...)
+	}
+}
+
+// WithEnvDir configures the directory of the default env files.
+// Defaults to the current working directory.
+func WithEnvDir(dir string) Option {
+	return func(o *options) {
+		o.envDir = dir
+	}
+}
+
+// WithoutDotEnv disables loading of env files.
+func WithoutDotEnv() Option {
+	return func(o *options) {
+		o.skipDotEnv = true
+	}
+}
+
+// WithEnvOverload makes the env files override the variables which are
+// already set in the environment. By default, the environment wins.
+func WithEnvOverload() Option {
+	return func(o *options) {
+		o.envOverload = true
+	}
+}
+
+// WithEnvSecrets resolves the env file values which are secret references,
+// e.g. DB_PASSWORD=gSecret://projects/p/secrets/db/versions/latest, before
+// setting them in the environment. So the environment holds the secret values
+// for the code which reads it directly.
+func WithEnvSecrets() Option {
+	return func(o *options) {
+		o.envSecrets = true
+	}
+}
+
+// WithIsolatedEnv keeps the variables of the env files for expanding the
+// configuration only, instead of setting them in the process environment.
+// It allows loading configurations with different env files in parallel, e.g.
+// in parallel tests.
+func WithIsolatedEnv() Option {
+	return func(o *options) {
+		o.isolatedEnv = true
+	}
+}
+
+// WithSecretClient configures the client used to fetch `gSecret://` secrets.
+// The client is owned by the caller and it is not closed after loading.
+func WithSecretClient(client *gcp.SecretClient) Option {
+	return func(o *options) {
+		o.secretClient = client
+	}
+}
+
+// WithClientOptions configures the options used to create the GCP secret
+// client. It is ignored if WithSecretClient is provided.
+func WithClientOptions(opts ...option.ClientOption) Option {
+	return func(o *options) {
+		o.clientOpts = append(o.clientOpts, opts...)
+	}
+}
+
+// WithTimeout configures the maximum duration to load the configuration,
+// including fetching the secrets.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLookupEnv configures the function used to lookup the environment
+// variables, for `${ENV_NAME}` and `env://` expansion and the VAULT_*
+// defaults of `vault://` references.
+// Defaults to os.LookupEnv.
+func WithLookupEnv(lookup LookupEnvFunc) Option {
+	return func(o *options) {
+		if lookup != nil {
+			o.lookupEnv = lookup
+		}
+	}
+}
+
+// WithDecodeHooks appends decode hooks to the built-in ones.
+// They run after environment variables and secrets are expanded.
+func WithDecodeHooks(hooks ...mapstructure.DecodeHookFunc) Option {
+	return func(o *options) {
+		o.decodeHooks = append(o.decodeHooks, hooks...)
+	}
+}
+
+// WithEnvOverrides enables overriding the config values with environment
+// variables derived from their mapstructure path. The path is upper cased,
+// dots are replaced with underscores and it is prefixed with the prefix,
+// e.g. nested.val1 is overridden by APP_NESTED_VAL1 with APP prefix.
+//
+// The values are used verbatim, e.g. `$` in passwords is kept, unless the
+// whole value is a secret reference, which is resolved.
+//
+// The precedence, from lowest to highest, is: `default` struct tags, config
+// file, environment variables and then flags.
+func WithEnvOverrides(prefix string) Option {
+	return func(o *options) {
+		o.envOverrides = true
+		o.envPrefix = prefix
+	}
+}
+
+// WithStrict fails loading if the config file has keys which do not map to any
+// config field, e.g. typos in key names.
+// The error is an InvalidArgument error with one field per unknown key.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithUnsetWarnings logs a warning for every config field which is not set by
+// any source. The warnings are recorded in the Report as well.
+func WithUnsetWarnings() Option {
+	return func(o *options) {
+		o.warnUnset = true
+	}
+}
+
+// WithSecretTimeout configures the maximum duration to fetch a single secret.
+func WithSecretTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.secretTimeout = timeout
+	}
+}
+
+// WithSecretConcurrency configures the maximum number of secrets fetched
+// concurrently. Defaults to 8.
+func WithSecretConcurrency(n int) Option {
+	return func(o *options) {
+		o.secretConcurrency = n
+	}
+}
+
+// WithSecretCache configures the cache used to store the fetched secrets.
+// Share the same cache between loads to avoid fetching the secrets again when
+// the configuration is reloaded.
+func WithSecretCache(cache *SecretCache) Option {
+	return func(o *options) {
+		o.secretCache = cache
+	}
+}
+
+// WithEnvironment configures the environment used to select the overlay
+// config file, e.g. config.staging.yaml for config.yaml and staging.
+// Pass an empty string to disable overlays.
+//
+// Defaults to APP_ENV or ENV environment variable.
+func WithEnvironment(environment string) Option {
+	return func(o *options) {
+		o.environment = &environment
+	}
+}
+
+// WithSchemaValidation validates the config files, merged with their includes
+// and overlay, against the JSON Schema of the config struct before decoding.
+// See Schema and ValidateFile.
+//
+// Required properties must be present in the files, even if they are
+// overridden by environment variables.
+func WithSchemaValidation() Option {
+	return func(o *options) {
+		o.validateSchema = true
+	}
+}
+
+// WithSchema validates the config files, merged with their includes and
+// overlay, against the given schema before decoding, e.g. a schema generated by
+// Schema and stored along with the config files.
+//
+// Combined with WithStrict, keys which are not described by the schema are
+// rejected as well, even when decoding into a map.
+func WithSchema(schema *JSONSchema) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithFlags overrides the config values with the flags which are set.
+// The flags must be defined with BindFlags and parsed before loading.
+// Flags have the highest precedence. Their values are used verbatim, unless
+// the whole value is a secret reference, which is resolved.
+func WithFlags(fs *pflag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = fs
+	}
+}
+
+// WithLocalSecrets resolves `gSecret://` secrets from the given map keyed by
+// the secret path, instead of GCP Secret Manager. See LocalSecretResolver.
+func WithLocalSecrets(secrets map[string]string) Option {
+	return func(o *options) {
+		o.localSecrets = NewLocalSecretResolver(secrets)
+	}
+}
+
+// WithLocalSecretsFile resolves `gSecret://` secrets from a YAML or JSON file
+// mapping the secret paths to their values, instead of GCP Secret Manager.
+// See NewLocalSecretResolverFromFile.
+//
+// It can also be enabled with FCONFIG_LOCAL_SECRETS environment variable.
+func WithLocalSecretsFile(file string) Option {
+	return func(o *options) {
+		o.localSecrets = NewLocalSecretResolverFromFile(file)
+	}
+}
+
+// WithDecryptionKey configures the age secret key used to decrypt the
+// `enc:age:<data>` values, e.g. AGE-SECRET-KEY-1...
+//
+// Defaults to FCONFIG_AGE_KEY environment variable.
+func WithDecryptionKey(key string) Option {
+	return func(o *options) {
+		o.decryptionKey = key
+	}
+}
+
+// WithDecryptionKeyFile configures the age identity file used to decrypt the
+// `enc:age:<data>` values.
+//
+// Defaults to FCONFIG_AGE_KEY_FILE environment variable.
+func WithDecryptionKeyFile(file string) Option {
+	return func(o *options) {
+		o.decryptionKeyFile = file
+	}
+}
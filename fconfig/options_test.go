@@ -0,0 +1,111 @@
+package fconfig
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+type optionsConfig struct {
+	EnvVar string `mapstructure:"envVar"`
+	Secret string `mapstructure:"secret"`
+}
+
+func TestLoadConfigWithOptions(t *testing.T) {
+	lookupEnv := func(key string) (string, bool) {
+		if key == "FCONFIG_TEST_LOOKUP" {
+			return "from-lookup", true
+		}
+		return "", false
+	}
+
+	slow := func(delay time.Duration) SecretResolver {
+		return SecretResolverFunc(func(ctx context.Context, ref string) (string, error) {
+			select {
+			case <-ctx.Done():
+				return "", ferrors.WithStack(ctx.Err())
+			case <-time.After(delay):
+				return ref, nil
+			}
+		})
+	}
+
+	upper := func(f reflect.Type, _ reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+		return strings.ToUpper(data.(string)), nil
+	}
+
+	testCases := []struct {
+		name    string
+		opts    []Option
+		want    *optionsConfig
+		wantErr bool
+	}{
+		{
+			name: "should use custom lookup env",
+			opts: []Option{
+				WithoutDotEnv(),
+				WithLookupEnv(lookupEnv),
+				WithSecretResolver("slow", slow(0)),
+			},
+			want: &optionsConfig{EnvVar: "from-lookup", Secret: "secret"},
+		},
+		{
+			name: "should run custom decode hooks after expansion",
+			opts: []Option{
+				WithoutDotEnv(),
+				WithLookupEnv(lookupEnv),
+				WithSecretResolver("slow", slow(0)),
+				WithDecodeHooks(upper),
+			},
+			want: &optionsConfig{EnvVar: "FROM-LOOKUP", Secret: "SECRET"},
+		},
+		{
+			name: "should fail when secrets take longer than timeout",
+			opts: []Option{
+				WithoutDotEnv(),
+				WithSecretResolver("slow", slow(time.Second)),
+				WithTimeout(10 * time.Millisecond),
+			},
+			wantErr: true,
+		},
+		{
+			name: "should fail when env file does not exist",
+			opts: []Option{
+				WithEnvFiles("testdata/missing.env"),
+				WithSecretResolver("slow", slow(0)),
+			},
+			wantErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			got := &optionsConfig{}
+
+			err := LoadConfigWithOptions(context.Background(), "testdata/configOptions.yaml", got,
+				tc.opts...)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected = %+v but, got = %+v", tc.want, got)
+			}
+		})
+	}
+}
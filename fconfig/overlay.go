@@ -0,0 +1,199 @@
+package fconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/spf13/viper"
+)
+
+// includeKey is the key of the include directive inside config files.
+const includeKey = "include"
+
+// environmentVars are the environment variables used to select the overlay
+// file, in order of precedence.
+var environmentVars = []string{"APP_ENV", "ENV"}
+
+// layeredConfig is the result of reading a config file with its includes and
+// overlay.
+type layeredConfig struct {
+	// settings are the merged settings.
+	settings map[string]interface{}
+
+	// files are the files read, in the order they are merged.
+	files []string
+
+	// hashes are the hashes of the content of the files keyed by their name.
+	hashes map[string]string
+}
+
+// fileHashes returns the files read along with the hashes of their content.
+func (lc *layeredConfig) fileHashes() []SnapshotFile {
+	files := make([]SnapshotFile, 0, len(lc.files))
+	for _, file := range lc.files {
+		files = append(files, SnapshotFile{Path: file, Hash: lc.hashes[file]})
+	}
+
+	return files
+}
+
+// readLayeredConfig reads the base config file and merges the overlay of the
+// environment on top of it, e.g. config.staging.yaml for config.yaml and
+// staging environment. The overlay is optional.
+//
+// Each file can include other files with the include directive, paths are
+// relative to the file:
+//
+//	include:
+//	  - common.yaml
+//	  - database.yaml
+//
+// The included files are merged in order, then the file itself is merged on
+// top of them.
+//
+// The merge policy is:
+//   - maps are merged recursively, key by key.
+//   - lists and scalar values are replaced as a whole.
+func readLayeredConfig(file, environment string) (*layeredConfig, error) {
+	lc := &layeredConfig{hashes: map[string]string{}}
+
+	settings, err := lc.readFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if environment != "" {
+		overlay := overlayFile(file, environment)
+
+		_, err = os.Stat(overlay)
+		switch {
+		case err == nil:
+			overlaySettings, err := lc.readFile(overlay, nil)
+			if err != nil {
+				return nil, err
+			}
+			mergeSettings(settings, overlaySettings)
+
+		case !os.IsNotExist(err):
+			return nil, ferrors.Wrapf(err, "unable to read overlay config: %s", overlay)
+		}
+	}
+
+	lc.settings = settings
+	return lc, nil
+}
+
+// readFile reads the file and its includes.
+// including holds the files being read, to detect include cycles.
+func (lc *layeredConfig) readFile(file string, including []string) (map[string]interface{}, error) {
+	for _, f := range including {
+		if f == file {
+			return nil, ferrors.Newf("include cycle detected: %s -> %s",
+				strings.Join(including, " -> "), file)
+		}
+	}
+	including = append(including[:len(including):len(including)], file)
+
+	v := viper.New()
+	v.SetConfigFile(file)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	own := v.AllSettings()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, ferrors.WithStack(err)
+	}
+	lc.hashes[file] = hashData(content)
+
+	includes, err := includePaths(own[includeKey], filepath.Dir(file))
+	if err != nil {
+		return nil, ferrors.Wrapf(err, "invalid include directive in %s", file)
+	}
+	delete(own, includeKey)
+
+	settings := map[string]interface{}{}
+	for _, include := range includes {
+		included, err := lc.readFile(include, including)
+		if err != nil {
+			return nil, err
+		}
+		mergeSettings(settings, included)
+	}
+
+	lc.files = append(lc.files, file)
+	mergeSettings(settings, own)
+
+	return settings, nil
+}
+
+// includePaths returns the paths of the include directive relative to dir.
+func includePaths(val interface{}, dir string) ([]string, error) {
+	var paths []string
+
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		paths = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, ferrors.Newf("include must be a list of paths, got: %v", item)
+			}
+			paths = append(paths, path)
+		}
+	default:
+		return nil, ferrors.Newf("include must be a path or a list of paths, got: %v", val)
+	}
+
+	for i, path := range paths {
+		if !filepath.IsAbs(path) {
+			paths[i] = filepath.Join(dir, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// overlayFile returns the overlay file of the environment,
+// e.g. config.staging.yaml for config.yaml.
+func overlayFile(file, environment string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "." + environment + ext
+}
+
+// mergeSettings merges src into dst. Maps are merged recursively, everything
+// else is replaced.
+func mergeSettings(dst, src map[string]interface{}) {
+	for key, srcVal := range src {
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		if srcIsMap && dstIsMap {
+			mergeSettings(dstMap, srcMap)
+			continue
+		}
+
+		dst[key] = srcVal
+	}
+}
+
+// detectEnvironment returns the environment from APP_ENV or ENV environment
+// variables.
+func detectEnvironment(lookupEnv LookupEnvFunc) string {
+	for _, name := range environmentVars {
+		if env, ok := lookupEnv(name); ok && env != "" {
+			return env
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,124 @@
+package fconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type overlayConfig struct {
+	Name     string   `mapstructure:"name"`
+	Region   string   `mapstructure:"region"`
+	Hosts    []string `mapstructure:"hosts"`
+	Database struct {
+		Host string `mapstructure:"host"`
+		Port int    `mapstructure:"port"`
+	} `mapstructure:"database"`
+}
+
+func TestLoadConfigOverlay(t *testing.T) {
+	testCases := []struct {
+		name      string
+		file      string
+		opts      []Option
+		want      *overlayConfig
+		wantFiles []string
+		wantErr   bool
+	}{
+		{
+			name: "should merge included files below the file",
+			file: "testdata/layered/config.yaml",
+			opts: []Option{WithEnvironment("")},
+			want: &overlayConfig{
+				Name:   "base",
+				Region: "ca",
+				Hosts:  []string{"base-1", "base-2"},
+				Database: struct {
+					Host string `mapstructure:"host"`
+					Port int    `mapstructure:"port"`
+				}{Host: "localhost", Port: 5432},
+			},
+			wantFiles: []string{
+				"testdata/layered/common.yaml",
+				"testdata/layered/config.yaml",
+			},
+		},
+		{
+			name: "should merge environment overlay and replace lists",
+			file: "testdata/layered/config.yaml",
+			opts: []Option{WithLookupEnv(func(key string) (string, bool) {
+				if key == "APP_ENV" {
+					return "staging", true
+				}
+				return "", false
+			})},
+			want: &overlayConfig{
+				Name:   "base",
+				Region: "ca",
+				Hosts:  []string{"staging-1"},
+				Database: struct {
+					Host string `mapstructure:"host"`
+					Port int    `mapstructure:"port"`
+				}{Host: "staging-host", Port: 5432},
+			},
+			wantFiles: []string{
+				"testdata/layered/common.yaml",
+				"testdata/layered/config.yaml",
+				"testdata/layered/config.staging.yaml",
+			},
+		},
+		{
+			name: "should ignore missing overlay",
+			file: "testdata/layered/config.yaml",
+			opts: []Option{WithEnvironment("prod")},
+			want: &overlayConfig{
+				Name:   "base",
+				Region: "ca",
+				Hosts:  []string{"base-1", "base-2"},
+				Database: struct {
+					Host string `mapstructure:"host"`
+					Port int    `mapstructure:"port"`
+				}{Host: "localhost", Port: 5432},
+			},
+			wantFiles: []string{
+				"testdata/layered/common.yaml",
+				"testdata/layered/config.yaml",
+			},
+		},
+		{
+			name:    "should detect include cycles",
+			file:    "testdata/layered/cycleA.yaml",
+			opts:    []Option{WithEnvironment("")},
+			wantErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			got := &overlayConfig{}
+			report := &Report{}
+
+			opts := append([]Option{WithoutDotEnv(), WithReport(report)}, tc.opts...)
+			err := LoadConfigWithOptions(context.Background(), tc.file, got, opts...)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected = %+v but, got = %+v", tc.want, got)
+			}
+
+			if !reflect.DeepEqual(report.Files, tc.wantFiles) {
+				t.Errorf("expected files = %v but, got = %v", tc.wantFiles, report.Files)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package fconfig
+
+import (
+	"fmt"
+
+	"github.com/Flahmingo-Investments/helpers-go/flog"
+)
+
+// ValueSource is where the final value of a config field came from.
+type ValueSource string
+
+// Sources of the config values, from the lowest to the highest precedence.
+const (
+	// SourceDefault is the value of the `default` struct tag.
+	SourceDefault ValueSource = "default"
+
+	// SourceFile is the value from the config file.
+	SourceFile ValueSource = "file"
+
+	// SourceEnv is the value from the environment variable override.
+	SourceEnv ValueSource = "env"
+
+	// SourceFlag is the value from the command line flag.
+	SourceFlag ValueSource = "flag"
+)
+
+// Report describes how a configuration was loaded.
+//
+// Pass it to WithReport to have it filled while loading.
+type Report struct {
+	// Files are the config files read, including the overlay and the included
+	// files, in the order they are merged.
+	Files []string
+
+	// Sources maps the dotted mapstructure path of each config field to where
+	// its final value came from. Fields which are not set by any source are
+	// not present.
+	Sources map[string]ValueSource
+
+	// Secrets are the secret references found in the configuration, as
+	// `<scheme>://<ref>`, sorted and without duplicates. Use ResolveSecret to
+	// check whether they are accessible. References built from the value of
+	// another secret are not known before resolving it, so they are not
+	// listed.
+	Secrets []string
+
+	// SecretsResolved is the number of distinct secrets resolved, including
+	// the ones found in the secret cache.
+	SecretsResolved int
+
+	// Warnings are the non fatal problems found while loading.
+	Warnings []string
+
+	// load is what is known about the loaded config, for Dump and Snapshot.
+	load *loadInfo
+}
+
+// warn logs the warning and records it in the report, if any.
+func (o *options) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	flog.Warnf("fconfig: %s", msg)
+
+	if o.report != nil {
+		o.report.Warnings = append(o.report.Warnings, msg)
+	}
+}
+
+// WithReport configures the report to fill while loading the configuration.
+func WithReport(report *Report) Option {
+	return func(o *options) {
+		o.report = report
+	}
+}
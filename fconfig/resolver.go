@@ -0,0 +1,263 @@
+package fconfig
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/Flahmingo-Investments/helpers-go/gcp"
+	"google.golang.org/api/option"
+)
+
+// Schemes of the built-in secret resolvers.
+// Only SchemeGSecret is enabled by default, see WithBuiltinResolvers.
+const (
+	SchemeGSecret = "gSecret"
+	SchemeFile    = "file"
+	SchemeEnv     = "env"
+	SchemeVault   = "vault"
+)
+
+// refRegex matches a secret reference in the form of `<scheme>://<ref>`.
+var refRegex = regexp.MustCompile(`^(?P<Scheme>[a-zA-Z][a-zA-Z0-9+.-]*)://(?P<Ref>.+)`)
+
+// SecretResolver resolves a secret reference into its value.
+//
+// The ref is everything after `<scheme>://`, e.g. for
+// `gSecret://projects/p/secrets/s/versions/latest` the ref is
+// `projects/p/secrets/s/versions/latest`.
+//
+// A resolver that holds resources may implement io.Closer, it will be closed
+// once the configuration is loaded.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc is an adapter to allow the use of ordinary functions as
+// SecretResolver.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve calls f(ctx, ref).
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// VersionedSecretResolver is a SecretResolver which knows the version of the
+// secrets it resolves, e.g. GCP Secret Manager resolves `latest` to a version
+// number. The versions are recorded in the config snapshot, see Snapshot.
+type VersionedSecretResolver interface {
+	SecretResolver
+
+	// ResolveVersion resolves the ref into the secret and its version.
+	ResolveVersion(ctx context.Context, ref string) (secret string, version string, err error)
+}
+
+// resolvers is a set of secret resolvers keyed by their URI scheme.
+type resolvers map[string]SecretResolver
+
+// defaultResolvers returns a fresh set of the built-in resolvers.
+func defaultResolvers(o *options) resolvers {
+	var gSecret SecretResolver = &gSecretResolver{
+		client:     o.secretClient,
+		clientOpts: o.clientOpts,
+		shared:     o.secretClient != nil,
+	}
+	if o.localSecrets != nil {
+		gSecret = o.localSecrets
+	}
+
+	r := resolvers{
+		SchemeGSecret: gSecret,
+		SchemeEncrypted: &encryptedResolver{
+			key:       o.decryptionKey,
+			keyFile:   o.decryptionKeyFile,
+			lookupEnv: o.lookupEnv,
+		},
+	}
+
+	// the other schemes are opt-in, since values such as `file://migrations`
+	// are common in configurations which predate them.
+	for _, scheme := range o.builtinResolvers {
+		switch scheme {
+		case SchemeFile:
+			r[SchemeFile] = fileResolver{}
+		case SchemeEnv:
+			r[SchemeEnv] = envResolver{lookupEnv: o.lookupEnv}
+		case SchemeVault:
+			r[SchemeVault] = &VaultResolver{lookupEnv: o.lookupEnv}
+		}
+	}
+
+	return r
+}
+
+// parse splits val into a scheme and a ref if val is a reference to one of the
+// registered resolvers.
+func (r resolvers) parse(val string) (string, string, bool) {
+	// encrypted values are in the form of `enc:<algorithm>:<data>`. Only the
+	// supported algorithm is matched, so values such as `enc:utf8` are kept.
+	if strings.HasPrefix(val, SchemeEncrypted+":"+algorithmAge+":") {
+		if _, ok := r[SchemeEncrypted]; ok {
+			return SchemeEncrypted, strings.TrimPrefix(val, SchemeEncrypted+":"), true
+		}
+	}
+
+	matches := refRegex.FindStringSubmatch(val)
+	if matches == nil {
+		return "", "", false
+	}
+
+	scheme := matches[refRegex.SubexpIndex("Scheme")]
+	if _, ok := r[scheme]; !ok || scheme == SchemeEncrypted {
+		return "", "", false
+	}
+
+	return scheme, matches[refRegex.SubexpIndex("Ref")], true
+}
+
+// resolve resolves the ref using the resolver registered for the scheme.
+func (r resolvers) resolve(ctx context.Context, scheme, ref string) (string, error) {
+	secret, _, err := r.resolveVersion(ctx, scheme, ref)
+	return secret, err
+}
+
+// resolveVersion is same as resolve but it returns the version of the secret
+// as well, if the resolver is a VersionedSecretResolver.
+func (r resolvers) resolveVersion(ctx context.Context, scheme, ref string) (string, string, error) {
+	var (
+		secret, version string
+		err             error
+	)
+
+	if versioned, ok := r[scheme].(VersionedSecretResolver); ok {
+		secret, version, err = versioned.ResolveVersion(ctx, ref)
+	} else {
+		secret, err = r[scheme].Resolve(ctx, ref)
+	}
+
+	if err != nil {
+		return "", "", ferrors.Wrapf(err, "unable to resolve %s secret: %s", scheme, ref)
+	}
+
+	return secret, version, nil
+}
+
+// close closes all the resolvers which hold resources.
+func (r resolvers) close() {
+	for _, resolver := range r {
+		if c, ok := resolver.(io.Closer); ok {
+			_ = c.Close()
+		}
+	}
+}
+
+// ResolveSecret resolves a single secret reference, e.g.
+// `gSecret://projects/p/secrets/s/versions/latest`, with the resolvers
+// configured by the options. It can be used to check whether the secrets
+// listed in Report.Secrets are accessible.
+//
+// It returns an InvalidArgument error if ref is not a reference to any of
+// the registered resolvers.
+func ResolveSecret(ctx context.Context, ref string, opts ...Option) (string, error) {
+	o := buildOptions(opts...)
+	defer o.closeResolvers()
+
+	scheme, secretRef, ok := o.resolvers.parse(ref)
+	if !ok {
+		return "", ferrors.NewInvalidArgumentError("not a secret reference: " + ref)
+	}
+
+	return o.resolvers.resolve(ctx, scheme, secretRef)
+}
+
+// gSecretResolver resolves `gSecret://` references from GCP Secret Manager.
+//
+// The client is initialized only when the first secret is resolved, so
+// configurations without secrets do not require GCP credentials.
+type gSecretResolver struct {
+	mu         sync.Mutex
+	client     *gcp.SecretClient
+	clientOpts []option.ClientOption
+
+	// shared is true when the client is provided by the caller, so it is not
+	// closed by the resolver.
+	shared bool
+}
+
+// Resolve fetches the secret from GCP Secret Manager.
+func (r *gSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secret, _, err := r.ResolveVersion(ctx, ref)
+	return secret, err
+}
+
+// ResolveVersion fetches the secret from GCP Secret Manager along with the
+// name of its version.
+func (r *gSecretResolver) ResolveVersion(ctx context.Context, ref string) (string, string, error) {
+	r.mu.Lock()
+	if r.client == nil {
+		client, err := gcp.NewSecretClient(r.clientOpts...)
+		if err != nil {
+			r.mu.Unlock()
+			return "", "", err
+		}
+		r.client = client
+	}
+	client := r.client
+	r.mu.Unlock()
+
+	return client.GetSecretWithVersionContext(ctx, ref)
+}
+
+// Close closes the underlying GCP client, if it was initialized by the
+// resolver.
+func (r *gSecretResolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil || r.shared {
+		return nil
+	}
+
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+// fileResolver resolves `file://` references by reading the file content,
+// e.g. Kubernetes secrets mounted as volumes.
+//
+// Example: file:///var/run/secrets/db-password
+type fileResolver struct{}
+
+// Resolve reads the file and returns its content without the trailing newline.
+func (fileResolver) Resolve(_ context.Context, ref string) (string, error) {
+	content, err := os.ReadFile(ref)
+	if err != nil {
+		return "", ferrors.WithStack(err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// envResolver resolves `env://` references from environment variables.
+//
+// Unlike `${ENV_NAME}`, it is an error if the variable is not set.
+//
+// Example: env://DB_PASSWORD
+type envResolver struct {
+	lookupEnv LookupEnvFunc
+}
+
+// Resolve returns the value of the environment variable.
+func (r envResolver) Resolve(_ context.Context, ref string) (string, error) {
+	val, ok := r.lookupEnv(ref)
+	if !ok {
+		return "", ferrors.NewNotFoundError("environment variable is not set: " + ref)
+	}
+
+	return val, nil
+}
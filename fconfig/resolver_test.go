@@ -0,0 +1,284 @@
+package fconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+func TestVaultResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"kv2-pass","user":"kv2-user"},` +
+				`"metadata":{"version":1}}}`))
+		case "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data":{"password":"kv1-pass"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	testCases := []struct {
+		name        string
+		ref         string
+		token       string
+		want        string
+		wantVersion string
+		wantCode    ferrors.ErrorCode
+		wantErr     bool
+	}{
+		{
+			name:        "should resolve kv version 2 secret",
+			ref:         "secret/data/app#password",
+			token:       "test-token",
+			want:        "kv2-pass",
+			wantVersion: "1",
+		},
+		{
+			name:  "should resolve kv version 1 secret without key",
+			ref:   "kv/app",
+			token: "test-token",
+			want:  "kv1-pass",
+		},
+		{
+			name:    "should require a key if secret has multiple keys",
+			ref:     "secret/data/app",
+			token:   "test-token",
+			wantErr: true,
+		},
+		{
+			name:     "should return not found for missing key",
+			ref:      "secret/data/app#missing",
+			token:    "test-token",
+			wantCode: ferrors.NotFound,
+			wantErr:  true,
+		},
+		{
+			name:     "should return not found for missing secret",
+			ref:      "secret/data/missing#password",
+			token:    "test-token",
+			wantCode: ferrors.NotFound,
+			wantErr:  true,
+		},
+		{
+			name:     "should return permission denied for invalid token",
+			ref:      "secret/data/app#password",
+			token:    "invalid",
+			wantCode: ferrors.PermissionDenied,
+			wantErr:  true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			r := &VaultResolver{Address: server.URL, Token: tc.token}
+
+			got, version, err := r.ResolveVersion(context.Background(), tc.ref)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got nil")
+					return
+				}
+				if tc.wantCode != 0 && ferrors.Code(err) != tc.wantCode {
+					t.Errorf("expected error code = %s but, got = %s", tc.wantCode, ferrors.Code(err))
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected error to be nil but, got error: %+v", err)
+				return
+			}
+
+			if got != tc.want {
+				t.Errorf("expected = %s but, got = %s", tc.want, got)
+			}
+			if version != tc.wantVersion {
+				t.Errorf("expected version = %s but, got = %s", tc.wantVersion, version)
+			}
+		})
+	}
+}
+
+func TestVaultResolverLookupEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "env-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"password":"kv1-pass"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	env := map[string]string{
+		"VAULT_ADDR":      server.URL,
+		"VAULT_TOKEN":     "env-token",
+		"VAULT_NAMESPACE": "team",
+	}
+
+	got, err := ResolveSecret(context.Background(), "vault://kv/app",
+		WithBuiltinResolvers(SchemeVault),
+		WithLookupEnv(func(key string) (string, bool) {
+			val, ok := env[key]
+			return val, ok
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if got != "kv1-pass" {
+		t.Errorf("expected = kv1-pass but, got = %s", got)
+	}
+}
+
+func TestLoadConfigWithResolvers(t *testing.T) {
+	type resolverConfig struct {
+		FileSecret   string `mapstructure:"fileSecret"`
+		EnvSecret    string `mapstructure:"envSecret"`
+		CustomSecret string `mapstructure:"customSecret"`
+		URL          string `mapstructure:"url"`
+	}
+
+	t.Setenv("FCONFIG_TEST_ENV_SECRET", "env-secret")
+
+	custom := SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "resolved-" + ref, nil
+	})
+
+	got := &resolverConfig{}
+	err := LoadConfig("testdata/configResolvers.yaml", got,
+		WithSecretResolver("test", custom),
+		WithBuiltinResolvers(SchemeFile, SchemeEnv),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	want := &resolverConfig{
+		FileSecret:   "db-password",
+		EnvSecret:    "env-secret",
+		CustomSecret: "resolved-custom-secret",
+		URL:          "https://example.com/path",
+	}
+	if *got != *want {
+		t.Errorf("expected = %+v but, got = %+v", want, got)
+	}
+}
+
+func TestLoadConfigWithUnsetEnvSecret(t *testing.T) {
+	type resolverConfig struct {
+		EnvSecret string `mapstructure:"envSecret"`
+	}
+
+	got := &resolverConfig{}
+	err := LoadConfig("testdata/configResolvers.yaml", got,
+		WithSecretResolver("test", SecretResolverFunc(func(context.Context, string) (string, error) {
+			return "", nil
+		})),
+		WithBuiltinResolvers(SchemeFile, SchemeEnv),
+	)
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestLoadConfigKeepsOptInSchemes(t *testing.T) {
+	type urlConfig struct {
+		Migrations string `mapstructure:"migrations"`
+		Hostname   string `mapstructure:"hostname"`
+		Env        string `mapstructure:"env"`
+	}
+
+	got := &urlConfig{}
+	err := LoadConfig("testdata/configURLs.yaml", got)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	want := &urlConfig{
+		Migrations: "file://migrations",
+		Hostname:   "file:///etc/hostname",
+		Env:        "env://HOME",
+	}
+	if *got != *want {
+		t.Errorf("expected = %+v but, got = %+v", want, got)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("FCONFIG_TEST_ENV_SECRET", "env-secret")
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr ferrors.ErrorCode
+	}{
+		{name: "file", ref: "file://testdata/secret.txt", want: "db-password"},
+		{name: "env", ref: "env://FCONFIG_TEST_ENV_SECRET", want: "env-secret"},
+		{name: "unset env", ref: "env://FCONFIG_TEST_UNSET", wantErr: ferrors.NotFound},
+		{name: "not a reference", ref: "plain-value", wantErr: ferrors.InvalidArgument},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveSecret(context.Background(), tc.ref,
+				WithoutDotEnv(),
+				WithBuiltinResolvers(SchemeFile, SchemeEnv),
+			)
+			if tc.wantErr != 0 {
+				if ferrors.Code(err) != tc.wantErr {
+					t.Errorf("expected error code %s but, got: %+v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected error to be nil but, got error: %+v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected = %s but, got = %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReportSecrets(t *testing.T) {
+	report := &Report{}
+	config := map[string]interface{}{}
+
+	t.Setenv("FCONFIG_TEST_ENV_SECRET", "env-secret")
+	err := LoadConfig("testdata/configResolvers.yaml", &config,
+		WithSecretResolver("test", SecretResolverFunc(
+			func(_ context.Context, ref string) (string, error) {
+				return ref, nil
+			},
+		)),
+		WithBuiltinResolvers(SchemeFile, SchemeEnv),
+		WithReport(report),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	want := []string{
+		"env://FCONFIG_TEST_ENV_SECRET",
+		"file://testdata/secret.txt",
+		"test://custom-secret",
+	}
+	if !reflect.DeepEqual(report.Secrets, want) {
+		t.Errorf("expected = %v but, got = %v", want, report.Secrets)
+	}
+}
@@ -0,0 +1,672 @@
+package fconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// jsonSchemaDraft is the JSON Schema version of the generated schemas.
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema describing a config struct.
+// It can be marshaled with encoding/json.
+type JSONSchema struct {
+	Schema      string        `json:"$schema,omitempty"`
+	Type        string        `json:"type,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Format      string        `json:"format,omitempty"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	MinItems  *int     `json:"minItems,omitempty"`
+	MaxItems  *int     `json:"maxItems,omitempty"`
+
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+
+	// Coerced are the other types accepted for the value, which are converted
+	// to Type while decoding, e.g. numeric strings for integers or comma
+	// separated strings for arrays. They are marshaled along with Type as the
+	// `type` keyword, e.g. "type": ["integer", "string"].
+	Coerced []string `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (s JSONSchema) MarshalJSON() ([]byte, error) {
+	type plain JSONSchema
+
+	var typ interface{} = s.Type
+	if len(s.Coerced) > 0 {
+		typ = append([]string{s.Type}, s.Coerced...)
+	}
+
+	return json.Marshal(struct {
+		Schema string      `json:"$schema,omitempty"`
+		Type   interface{} `json:"type,omitempty"`
+		*plain
+	}{
+		Schema: s.Schema,
+		Type:   typ,
+		plain:  (*plain)(&s),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// A list of types is read as Type followed by Coerced.
+func (s *JSONSchema) UnmarshalJSON(data []byte) error {
+	type plain JSONSchema
+
+	in := struct {
+		Type interface{} `json:"type"`
+		*plain
+	}{plain: (*plain)(s)}
+
+	err := json.Unmarshal(data, &in)
+	if err != nil {
+		return err
+	}
+
+	switch typ := in.Type.(type) {
+	case string:
+		s.Type = typ
+	case []interface{}:
+		for i, item := range typ {
+			name, _ := item.(string)
+			if i == 0 {
+				s.Type = name
+				continue
+			}
+			s.Coerced = append(s.Coerced, name)
+		}
+	}
+
+	return nil
+}
+
+// Schema generates a JSON Schema from the config struct.
+//
+// Property names are taken from the `mapstructure` tag, descriptions from the
+// `desc` tag and default values from the `default` tag. The validation rules
+// of the `validate` and `fconfig` tags are translated to their JSON Schema
+// equivalent, see Validate.
+//
+// Values are decoded weakly, so the schema also accepts the values which are
+// converted while decoding, e.g. "8080" for integers or "a,b" for arrays. Such
+// properties list the other types in their `type`, see JSONSchema.Coerced.
+//
+// Example:
+//
+//	schema, err := fconfig.Schema(&Config{})
+//	if err != nil {
+//		return err
+//	}
+//	out, err := json.MarshalIndent(schema, "", "  ")
+func Schema(config interface{}) (*JSONSchema, error) {
+	t := reflect.TypeOf(config)
+	if t == nil || indirectType(t).Kind() != reflect.Struct {
+		return nil, ferrors.NewInvalidArgumentError("config must be a struct or a pointer to a struct")
+	}
+
+	schema := typeSchema(indirectType(t), map[reflect.Type]bool{})
+	schema.Schema = jsonSchemaDraft
+
+	return schema, nil
+}
+
+// Patterns of the strings which are converted to the type of the value while
+// decoding, see strconv.ParseBool, strconv.ParseInt and strconv.ParseFloat.
+const (
+	booleanPattern = `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`
+	integerPattern = `^[+-]?[0-9]+$`
+	numberPattern  = `^[+-]?([0-9]+[.]?[0-9]*|[.][0-9]+)([eE][+-]?[0-9]+)?$`
+)
+
+// typeSchema returns the schema of the type.
+//
+// Values are decoded weakly, so the schema accepts the other types which are
+// converted while decoding as well, e.g. numeric strings for integers.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	t = indirectType(t)
+
+	switch {
+	case t == durationType:
+		return &JSONSchema{
+			Type:        "integer",
+			Coerced:     []string{"string"},
+			Description: "duration, e.g. 1m30s",
+		}
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case isTextType(t) && isIntegerKind(t.Kind()):
+		// e.g. ByteSize or zapcore.Level, decoded from 10MiB or 1024.
+		return &JSONSchema{Type: "integer", Coerced: []string{"string"}}
+	case isTextType(t):
+		// decoded from a string, e.g. url.URL or []byte.
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch {
+	case t.Kind() == reflect.Bool:
+		return &JSONSchema{Type: "boolean", Coerced: []string{"string"}, Pattern: booleanPattern}
+
+	case isIntegerKind(t.Kind()):
+		return &JSONSchema{Type: "integer", Coerced: []string{"string"}, Pattern: integerPattern}
+
+	case t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		return &JSONSchema{Type: "number", Coerced: []string{"string"}, Pattern: numberPattern}
+
+	case t.Kind() == reflect.String:
+		return &JSONSchema{Type: "string"}
+
+	case t.Kind() == reflect.Slice:
+		// comma separated strings are split into slices.
+		return &JSONSchema{
+			Type:    "array",
+			Coerced: []string{"string"},
+			Items:   typeSchema(t.Elem(), visiting),
+		}
+
+	case t.Kind() == reflect.Array:
+		return &JSONSchema{Type: "array", Items: typeSchema(t.Elem(), visiting)}
+
+	case t.Kind() == reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), visiting)}
+
+	case t.Kind() == reflect.Struct:
+		return structSchema(t, visiting)
+	}
+
+	// interface{} and other types accept anything.
+	return &JSONSchema{}
+}
+
+// isIntegerKind reports whether k is a signed or unsigned integer kind.
+func isIntegerKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Int64) || (k >= reflect.Uint && k <= reflect.Uint64)
+}
+
+// structSchema returns the schema of the struct type.
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+
+	// guard against recursive types.
+	if visiting[t] {
+		return schema
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name, squash := fieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		if squash && isNestedStruct(sf.Type) {
+			embedded := structSchema(indirectType(sf.Type), visiting)
+			for n, p := range embedded.Properties {
+				schema.Properties[n] = p
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		prop := typeSchema(sf.Type, visiting)
+		if desc := sf.Tag.Get("desc"); desc != "" {
+			prop.Description = desc
+		}
+
+		def, hasDefault := sf.Tag.Lookup("default")
+		if hasDefault {
+			prop.Default = defaultValue(prop, def)
+		}
+
+		for _, rule := range fieldRules(sf) {
+			if rule == "required" {
+				// a default value satisfies required.
+				if !hasDefault {
+					schema.Required = append(schema.Required, name)
+				}
+				continue
+			}
+			applyRule(prop, rule)
+		}
+
+		schema.Properties[name] = prop
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
+
+// defaultValue converts the `default` tag to the type of the schema.
+func defaultValue(schema *JSONSchema, def string) interface{} {
+	switch schema.Type {
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	case "integer":
+		if n, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(def, 64); err == nil {
+			return n
+		}
+	case "array":
+		items := []interface{}{}
+		for _, item := range strings.Split(def, ",") {
+			items = append(items, defaultValue(schema.Items, item))
+		}
+		return items
+	}
+
+	return def
+}
+
+// applyRule translates the validation rule to its JSON Schema equivalent.
+func applyRule(schema *JSONSchema, rule string) {
+	name, param := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, param = rule[:i], rule[i+1:]
+	}
+
+	switch name {
+	case "url":
+		schema.Format = "uri"
+
+	case "oneof":
+		for _, val := range strings.Fields(param) {
+			schema.Enum = append(schema.Enum, defaultValue(schema, val))
+		}
+
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			// e.g. durations, which can not be expressed in the schema.
+			return
+		}
+
+		setMin, setMax := name == "min" || name == "len", name == "max" || name == "len"
+		length := int(n)
+
+		switch schema.Type {
+		case "integer", "number":
+			if setMin {
+				schema.Minimum = &n
+			}
+			if setMax {
+				schema.Maximum = &n
+			}
+		case "string":
+			if setMin {
+				schema.MinLength = &length
+			}
+			if setMax {
+				schema.MaxLength = &length
+			}
+		case "array":
+			if setMin {
+				schema.MinItems = &length
+			}
+			if setMax {
+				schema.MaxItems = &length
+			}
+		}
+	}
+}
+
+// ValidateFile validates the config file, with its included files, against
+// the JSON Schema of the config struct without decoding it.
+//
+// It returns an InvalidArgument error with one field per failing config path.
+func ValidateFile(file string, config interface{}) error {
+	schema, err := Schema(config)
+	if err != nil {
+		return err
+	}
+
+	lc, err := readLayeredConfig(file, "")
+	if err != nil {
+		return err
+	}
+
+	return schema.ValidateSettings(lc.settings)
+}
+
+// ValidateSettings validates the raw settings, as parsed from YAML or JSON,
+// against the schema.
+//
+// Strings holding environment variables or secret references, e.g.
+// "${PORT}", are accepted for any type since they are expanded while
+// decoding. Property names are matched case insensitively, same as viper.
+//
+// It returns an InvalidArgument error with one field per failing config path.
+func (s *JSONSchema) ValidateSettings(settings map[string]interface{}) error {
+	var violations []ferrors.Field
+	s.validate("", settings, &violations)
+
+	if len(violations) > 0 {
+		return ferrors.NewInvalidArgumentError("configuration does not match the schema", violations...)
+	}
+
+	return nil
+}
+
+// validate validates val against the schema and appends the violations.
+func (s *JSONSchema) validate(path string, val interface{}, violations *[]ferrors.Field) {
+	violate := func(format string, args ...interface{}) {
+		name := path
+		if name == "" {
+			name = "."
+		}
+		*violations = append(*violations, ferrors.Field{
+			Name:        name,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if str, ok := val.(string); ok && s.Type != "string" && s.Type != "" && refRegex.MatchString(str) {
+		return
+	}
+
+	if str, ok := val.(string); ok && strings.Contains(str, "$") {
+		// expanded while decoding.
+		return
+	}
+
+	if !s.matchesType(val) {
+		types := append([]string{s.Type}, s.Coerced...)
+		violate("must be of type %s, got %T", strings.Join(types, " or "), val)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(val) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violate("must be one of %v", s.Enum)
+		}
+	}
+
+	if str, ok := val.(string); ok && s.Type != "string" && s.Type != "" {
+		s.validateCoerced(path, str, violate, violations)
+		return
+	}
+
+	switch v := val.(type) {
+	case string:
+		if s.Pattern != "" {
+			if ok, err := regexp.MatchString(s.Pattern, v); err != nil || !ok {
+				violate("must match pattern %s", s.Pattern)
+			}
+		}
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			violate("length must be at least %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(v) > *s.MaxLength {
+			violate("length must be at most %d", *s.MaxLength)
+		}
+		if s.Format == "uri" {
+			u, err := url.Parse(v)
+			if err != nil || u.Scheme == "" {
+				violate("must be a valid URL")
+			}
+		}
+
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			violate("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			violate("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+
+	case map[string]interface{}:
+		s.validateObject(path, v, violations)
+
+	default:
+		if n, ok := toFloat(val); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				violate("must be at least %v", *s.Minimum)
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				violate("must be at most %v", *s.Maximum)
+			}
+		}
+	}
+}
+
+// validateCoerced validates a string which is converted to the type of the
+// schema while decoding, see Coerced.
+func (s *JSONSchema) validateCoerced(
+	path, str string,
+	violate func(format string, args ...interface{}),
+	violations *[]ferrors.Field,
+) {
+	if s.Pattern != "" {
+		if ok, err := regexp.MatchString(s.Pattern, str); err != nil || !ok {
+			violate("must be of type %s or match pattern %s", s.Type, s.Pattern)
+			return
+		}
+	}
+
+	switch s.Type {
+	case "integer", "number":
+		// strings without numeric value, e.g. 10MiB for ByteSize, have no bounds
+		// to check.
+		n, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			violate("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			violate("must be at most %v", *s.Maximum)
+		}
+
+	case "array":
+		items := []interface{}{}
+		for _, item := range strings.Split(str, ",") {
+			items = append(items, item)
+		}
+		s.validate(path, items, violations)
+	}
+}
+
+// validateObject validates the properties of an object.
+func (s *JSONSchema) validateObject(
+	path string,
+	obj map[string]interface{},
+	violations *[]ferrors.Field,
+) {
+	// viper lower cases the keys, so match the properties case insensitively.
+	values := make(map[string]interface{}, len(obj))
+	for key, val := range obj {
+		values[strings.ToLower(key)] = val
+	}
+
+	for _, name := range s.Required {
+		if _, ok := values[strings.ToLower(name)]; !ok {
+			*violations = append(*violations, ferrors.Field{
+				Name:        joinPath(path, name),
+				Description: "is required",
+			})
+		}
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if val, ok := values[strings.ToLower(name)]; ok && val != nil {
+			s.Properties[name].validate(joinPath(path, name), val, violations)
+		}
+	}
+
+	if s.AdditionalProperties != nil {
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s.AdditionalProperties.validate(joinPath(path, key), obj[key], violations)
+		}
+	}
+}
+
+// unknownKeys returns the paths of the keys inside val which are not
+// described by the schema. Objects with additionalProperties accept any key.
+func (s *JSONSchema) unknownKeys(path string, val interface{}) []string {
+	var keys []string
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if s.Type != "object" {
+			return nil
+		}
+
+		props := make(map[string]*JSONSchema, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[strings.ToLower(name)] = prop
+		}
+
+		for key, item := range v {
+			prop, ok := props[strings.ToLower(key)]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				keys = append(keys, joinPath(path, key))
+				continue
+			}
+			keys = append(keys, prop.unknownKeys(joinPath(path, key), item)...)
+		}
+
+	case []interface{}:
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			keys = append(keys, s.Items.unknownKeys(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+	}
+
+	return keys
+}
+
+// matchesType reports whether val is of the type of the schema, or one of the
+// types which are converted to it.
+func (s *JSONSchema) matchesType(val interface{}) bool {
+	if matchesType(s.Type, val) {
+		return true
+	}
+
+	for _, typ := range s.Coerced {
+		if matchesType(typ, val) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesType reports whether val is of the JSON Schema type.
+func matchesType(typ string, val interface{}) bool {
+	switch typ {
+	case "":
+		return true
+	case "string":
+		_, ok := val.(string)
+		return ok
+	case "boolean":
+		_, ok := val.(bool)
+		return ok
+	case "integer":
+		n, ok := toFloat(val)
+		return ok && n == float64(int64(n))
+	case "number":
+		_, ok := toFloat(val)
+		return ok
+	case "array":
+		_, ok := val.([]interface{})
+		return ok
+	case "object":
+		_, ok := val.(map[string]interface{})
+		return ok
+	}
+
+	return false
+}
+
+// toFloat converts the numeric value to float64.
+func toFloat(val interface{}) (float64, bool) {
+	switch n := val.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
@@ -0,0 +1,237 @@
+package fconfig
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+type schemaConfig struct {
+	Name    string        `mapstructure:"name" desc:"name of the service" validate:"required"`
+	Port    int           `mapstructure:"port" default:"8080" validate:"required,min=1,max=65535"`
+	Env     string        `mapstructure:"env" validate:"oneof=dev prod"`
+	Hosts   []string      `mapstructure:"hosts" validate:"min=1"`
+	DBUri   string        `mapstructure:"dbUri" validate:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Labels  map[string]string
+	Nested  struct {
+		Val1 string `mapstructure:"val1" fconfig:"required"`
+		Val2 int    `mapstructure:"val2"`
+	} `mapstructure:"nested"`
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	got, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	for _, want := range []string{
+		`"$schema":"https://json-schema.org/draft/2020-12/schema"`,
+		`"name":{"type":"string","description":"name of the service"}`,
+		`"port":{"type":["integer","string"],"pattern":"^[+-]?[0-9]+$",` +
+			`"default":8080,"minimum":1,"maximum":65535}`,
+		`"env":{"type":"string","enum":["dev","prod"]}`,
+		`"hosts":{"type":["array","string"],"minItems":1,"items":{"type":"string"}}`,
+		`"dbUri":{"type":"string","format":"uri"}`,
+		`"Labels":{"type":"object","additionalProperties":{"type":"string"}}`,
+		`"required":["name"]`,
+		`"required":["val1"]`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected schema to contain %s but, got: %s", want, got)
+		}
+	}
+
+	if _, err := Schema("not a struct"); err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	err := ValidateFile("testdata/configSchema.yaml", &schemaConfig{})
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+
+	if ferrors.Code(err) != ferrors.InvalidArgument {
+		t.Errorf("expected error code = %s but, got = %s", ferrors.InvalidArgument, ferrors.Code(err))
+	}
+
+	for _, want := range []string{"port", "env", "hosts", "nested.val1"} {
+		if !strings.Contains(err.Error(), want+": ") {
+			t.Errorf("expected error to contain %s but, got: %v", want, err)
+		}
+	}
+
+	for _, notWant := range []string{"dbUri", "name"} {
+		if strings.Contains(err.Error(), notWant+": ") {
+			t.Errorf("expected error not to contain %s but, got: %v", notWant, err)
+		}
+	}
+}
+
+func TestLoadConfigWithSchemaValidation(t *testing.T) {
+	err := LoadConfigWithOptions(context.Background(), "testdata/configSchema.yaml", &schemaConfig{},
+		WithoutDotEnv(),
+		WithSchemaValidation(),
+	)
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestLoadConfigWithSchema(t *testing.T) {
+	schema, err := Schema(&strictConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	tests := []struct {
+		name   string
+		strict bool
+		want   []string
+	}{
+		{name: "accepts unknown keys", strict: false},
+		{
+			name:   "rejects unknown keys in strict mode",
+			strict: true,
+			want:   []string{"nested.val4", "yamltestvra"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []Option{WithoutDotEnv(), WithSchema(schema)}
+			if tc.strict {
+				opts = append(opts, WithStrict())
+			}
+
+			// decoding into a map has no struct to detect the unknown keys.
+			config := map[string]interface{}{}
+			err := LoadConfigWithOptions(context.Background(), "testdata/configStrict.yaml", &config,
+				opts...)
+
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("expected error to be nil but, got error: %+v", err)
+				}
+				return
+			}
+
+			if ferrors.Code(err) != ferrors.InvalidArgument {
+				t.Fatalf("expected InvalidArgument error but, got: %+v", err)
+			}
+			for _, key := range tc.want {
+				if !strings.Contains(err.Error(), key) {
+					t.Errorf("expected error to mention %s but, got: %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaWeakDecoding(t *testing.T) {
+	type weakConfig struct {
+		Size  ByteSize `mapstructure:"size"`
+		Port  int      `mapstructure:"port" validate:"max=65535"`
+		Ratio float64  `mapstructure:"ratio"`
+		Debug bool     `mapstructure:"debug"`
+		Hosts []int    `mapstructure:"hosts"`
+	}
+
+	testCases := []struct {
+		name       string
+		content    string
+		wantFields []string
+	}{
+		{
+			name:    "should accept the values converted while decoding",
+			content: "size: 1024\nport: \"8080\"\nratio: \"0.5\"\ndebug: \"true\"\nhosts: \"1,2\"\n",
+		},
+		{
+			name:    "should accept the values of the type",
+			content: "size: \"10MiB\"\nport: 8080\nratio: 0.5\ndebug: true\nhosts: [1, 2]\n",
+		},
+		{
+			name:       "should reject the strings which are not converted",
+			content:    "size: true\nport: \"abc\"\nratio: \"x\"\ndebug: \"maybe\"\nhosts: \"1,b\"\n",
+			wantFields: []string{"debug", "hosts[1]", "port", "ratio", "size"},
+		},
+		{
+			name:       "should check the bounds of numeric strings",
+			content:    "port: \"70000\"\n",
+			wantFields: []string{"port"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tc.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &weakConfig{}
+			err := LoadConfigWithOptions(context.Background(), file, got,
+				WithoutDotEnv(),
+				WithSchemaValidation(),
+			)
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Errorf("expected error to be nil but, got error: %+v", err)
+				}
+				return
+			}
+
+			if ferrors.Code(err) != ferrors.InvalidArgument {
+				t.Fatalf("expected InvalidArgument error but, got: %+v", err)
+			}
+			for _, field := range tc.wantFields {
+				if !strings.Contains(err.Error(), field+": ") {
+					t.Errorf("expected error to contain %s but, got: %v", field, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaJSON(t *testing.T) {
+	schema, err := Schema(&schemaConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	got := &JSONSchema{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	port := got.Properties["port"]
+	if port == nil || port.Type != "integer" || !reflect.DeepEqual(port.Coerced, []string{"string"}) ||
+		port.Pattern != integerPattern {
+		t.Errorf("expected port to accept integers and numeric strings but, got = %+v", port)
+	}
+	if got.Type != "object" || got.Schema != jsonSchemaDraft {
+		t.Errorf("expected the type and the $schema to be kept but, got = %+v", got)
+	}
+}
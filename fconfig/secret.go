@@ -0,0 +1,61 @@
+package fconfig
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// redacted replaces the secret values in logs and dumps.
+const redacted = "[REDACTED]"
+
+// compile time check.
+var (
+	_ fmt.Stringer   = Secret("")
+	_ fmt.GoStringer = Secret("")
+	_ json.Marshaler = Secret("")
+)
+
+// Secret is a string which is redacted when it is printed, logged or
+// marshaled, so it does not leak by accident.
+//
+// zap logs it using its String method, or MarshalJSON when the value is
+// reflected, so it is redacted as well.
+//
+// Example:
+//
+//	type Config struct {
+//		DBPassword fconfig.Secret `mapstructure:"db_password"`
+//	}
+//
+//	db.Connect(cfg.DBPassword.Value())
+type Secret string
+
+// Value returns the secret value.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer interface and returns a redacted value.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString implements fmt.GoStringer interface and returns a redacted value.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("fconfig.Secret(%q)", s.String())
+}
+
+// MarshalJSON implements json.Marshaler interface and returns a redacted
+// value.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML implements yaml.Marshaler interface and returns a redacted
+// value.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
@@ -0,0 +1,100 @@
+package fconfig
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// ConfigSnapshot records how a configuration was loaded, so it can be
+// reproduced later, e.g. while investigating an incident.
+// It holds no secret values and can be marshaled with encoding/json.
+type ConfigSnapshot struct {
+	// Timestamp is the time the configuration was loaded.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Config is the effective configuration with the secrets redacted, same
+	// as Dump.
+	Config interface{} `json:"config"`
+
+	// Files are the config files read with the hashes of their content, in
+	// the order they are merged.
+	Files []SnapshotFile `json:"files"`
+
+	// EnvOverrides are the names of the environment variables keyed by the
+	// config paths they override, see WithEnvOverrides.
+	EnvOverrides map[string]string `json:"envOverrides,omitempty"`
+
+	// Secrets are the secrets resolved, sorted by their reference.
+	Secrets []SnapshotSecret `json:"secrets,omitempty"`
+}
+
+// SnapshotFile is a config file read while loading a configuration.
+type SnapshotFile struct {
+	// Path of the file, or the name of the Source.
+	Path string `json:"path"`
+
+	// Hash is the SHA-256 hash of the content, e.g. sha256:<hex>.
+	Hash string `json:"hash"`
+}
+
+// SnapshotSecret is a secret resolved while loading a configuration.
+type SnapshotSecret struct {
+	// Ref is the secret reference as written in the config, e.g.
+	// gSecret://projects/p/secrets/s/versions/latest.
+	Ref string `json:"ref"`
+
+	// Version is the version of the secret value, e.g.
+	// projects/123/secrets/s/versions/5 for GCP Secret Manager. It is empty if
+	// the resolver is not a VersionedSecretResolver.
+	Version string `json:"version,omitempty"`
+}
+
+// Snapshot returns the snapshot of a loaded config, i.e. the effective config
+// with the secrets redacted, the files read with their hashes, the environment
+// variable overrides and the versions of the secrets resolved.
+//
+// Pass the report filled while loading the config, see WithReport and
+// LoadWithMetadata. It returns a FailedPrecondition error if the report was
+// not filled by a load.
+//
+// Example:
+//
+//	cfg, md, err := fconfig.LoadWithMetadata[Config]("config.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	snapshot, err := fconfig.Snapshot(&cfg, &md.Report)
+func Snapshot(config interface{}, report *Report) (*ConfigSnapshot, error) {
+	if report == nil || report.load == nil {
+		return nil, ferrors.WithCode(ferrors.FailedPrecondition,
+			"report was not filled by loading the config")
+	}
+	info := report.load
+
+	secrets := make([]SnapshotSecret, 0, len(info.secretVersions))
+	for ref, version := range info.secretVersions {
+		secrets = append(secrets, SnapshotSecret{Ref: ref, Version: version})
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Ref < secrets[j].Ref })
+
+	return &ConfigSnapshot{
+		Timestamp:    info.loadedAt,
+		Config:       redactedTree(config, report),
+		Files:        info.files,
+		EnvOverrides: info.envOverrides,
+		Secrets:      secrets,
+	}, nil
+}
+
+// JSON returns the snapshot as indented JSON.
+func (s *ConfigSnapshot) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to marshal config snapshot")
+	}
+
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package fconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// versionedResolver resolves every ref to a secret of version 7.
+type versionedResolver struct{}
+
+func (r versionedResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secret, _, err := r.ResolveVersion(ctx, ref)
+	return secret, err
+}
+
+func (versionedResolver) ResolveVersion(_ context.Context, ref string) (string, string, error) {
+	return "secret-" + ref, ref + "/versions/7", nil
+}
+
+func TestSnapshot(t *testing.T) {
+	env := map[string]string{"APP_NAME": "from-env"}
+	lookup := func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+
+	cfg := &dumpConfig{}
+	report := &Report{}
+	err := LoadConfigWithOptions(context.Background(), "testdata/configDump.yaml", cfg,
+		WithReport(report),
+		WithoutDotEnv(),
+		WithLookupEnv(lookup),
+		WithEnvOverrides("APP"),
+		WithSecretResolver("test", versionedResolver{}),
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	snapshot, err := Snapshot(cfg, report)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if snapshot.Timestamp.IsZero() {
+		t.Errorf("expected timestamp to be set")
+	}
+
+	content, err := os.ReadFile("testdata/configDump.yaml")
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	sum := sha256.Sum256(content)
+	wantFiles := []SnapshotFile{
+		{Path: "testdata/configDump.yaml", Hash: "sha256:" + hex.EncodeToString(sum[:])},
+	}
+	if !reflect.DeepEqual(snapshot.Files, wantFiles) {
+		t.Errorf("expected files = %+v but, got = %+v", wantFiles, snapshot.Files)
+	}
+
+	wantOverrides := map[string]string{"name": "APP_NAME"}
+	if !reflect.DeepEqual(snapshot.EnvOverrides, wantOverrides) {
+		t.Errorf("expected env overrides = %v but, got = %v", wantOverrides, snapshot.EnvOverrides)
+	}
+
+	wantSecrets := []SnapshotSecret{
+		{Ref: "test://api-key", Version: "api-key/versions/7"},
+		{Ref: "test://db-pass", Version: "db-pass/versions/7"},
+		{Ref: "test://nested", Version: "nested/versions/7"},
+	}
+	if !reflect.DeepEqual(snapshot.Secrets, wantSecrets) {
+		t.Errorf("expected secrets = %+v but, got = %+v", wantSecrets, snapshot.Secrets)
+	}
+
+	out, err := snapshot.JSON()
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if strings.Contains(string(out), "secret-") || strings.Contains(string(out), "token-value") {
+		t.Errorf("expected secret values to be redacted but, got:\n%s", out)
+	}
+	if !strings.Contains(string(out), `"name": "from-env"`) {
+		t.Errorf("expected the effective config but, got:\n%s", out)
+	}
+
+	_, err = Snapshot(&dumpConfig{}, &Report{})
+	if ferrors.Code(err) != ferrors.FailedPrecondition {
+		t.Errorf("expected error code = %s but, got = %s", ferrors.FailedPrecondition, ferrors.Code(err))
+	}
+}
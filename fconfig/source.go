@@ -0,0 +1,433 @@
+package fconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// defaultSourceFormat is the format of the sources which do not specify one.
+const defaultSourceFormat = "yaml"
+
+// gcsEndpoint is the endpoint of the GCS XML API.
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// gcsReadScope is the OAuth2 scope required to read GCS objects.
+const gcsReadScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// SourceContent is the content of a configuration read from a Source.
+type SourceContent struct {
+	// Data is the raw configuration.
+	Data []byte
+
+	// Format is the viper config type of the data, e.g. yaml or json.
+	// Defaults to yaml.
+	Format string
+
+	// Version identifies the content, e.g. the ETag of an HTTP response.
+	// Contents with the same version are assumed to be the same. Defaults to
+	// the hash of the data.
+	Version string
+}
+
+// Source provides a configuration from outside of the local file system,
+// e.g. an HTTP server or an object storage.
+//
+// Unlike config files, the configuration read from a source can not include
+// other files and has no environment overlay.
+type Source interface {
+	// Name identifies the source in errors and in Report.Files, e.g. its URL.
+	Name() string
+
+	// Read reads the configuration.
+	Read(ctx context.Context) (*SourceContent, error)
+}
+
+// LoadSource loads the configuration read from the source and unmarshal it
+// into the provided config, same as LoadConfigWithOptions.
+//
+// Example:
+//
+//	src := fconfig.NewHTTPSource("https://config.example.com/service.yaml")
+//	err := fconfig.LoadSource(ctx, src, &cfg)
+func LoadSource(ctx context.Context, src Source, config interface{}, opts ...Option) error {
+	o := buildOptions(opts...)
+
+	ctx, cancel, err := o.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	content, err := src.Read(ctx)
+	if err != nil {
+		return ferrors.Wrapf(err, "unable to read config from %s", src.Name())
+	}
+
+	return loadSourceContent(ctx, src.Name(), content, config, o)
+}
+
+// WatchSource polls the source every interval until the context is done.
+//
+// onChange is called with the initial configuration and then with a new
+// configuration every time the version of the source content changes. It is
+// called with the error if the source can not be read or its content can not
+// be loaded, in which case the previous configuration should be kept.
+//
+// It always returns the error of the context.
+//
+// Example:
+//
+//	go fconfig.WatchSource(ctx, src, time.Minute, func(cfg Config, err error) {
+//		if err != nil {
+//			flog.Errorf("unable to reload config: %v", err)
+//			return
+//		}
+//		current.Store(cfg)
+//	})
+func WatchSource[T any](
+	ctx context.Context,
+	src Source,
+	interval time.Duration,
+	onChange func(T, error),
+	opts ...Option,
+) error {
+	var (
+		zero        T
+		lastVersion string
+		loadedOnce  bool
+	)
+
+	poll := func() {
+		o := buildOptions(opts...)
+
+		ctx, cancel, err := o.begin(ctx)
+		if err != nil {
+			onChange(zero, err)
+			return
+		}
+		defer cancel()
+
+		content, err := src.Read(ctx)
+		if err != nil {
+			onChange(zero, ferrors.Wrapf(err, "unable to read config from %s", src.Name()))
+			return
+		}
+
+		version := contentVersion(content)
+		if loadedOnce && version == lastVersion {
+			return
+		}
+
+		// remember the version even if it fails to load, so the same broken
+		// content is reported once.
+		lastVersion, loadedOnce = version, true
+
+		config, target := newConfig[T]()
+		err = loadSourceContent(ctx, src.Name(), content, target, o)
+		if err != nil {
+			onChange(zero, err)
+			return
+		}
+
+		onChange(*config, nil)
+	}
+
+	poll()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			poll()
+		}
+	}
+}
+
+// loadSourceContent parses the content and decodes it into the config.
+func loadSourceContent(
+	ctx context.Context,
+	name string,
+	content *SourceContent,
+	config interface{},
+	opts *options,
+) error {
+	format := content.Format
+	if format == "" {
+		format = defaultSourceFormat
+	}
+
+	v := viper.New()
+	v.SetConfigType(format)
+
+	err := v.ReadConfig(bytes.NewReader(content.Data))
+	if err != nil {
+		return ferrors.Wrapf(err, "unable to parse config from %s", name)
+	}
+
+	settings := v.AllSettings()
+	if _, ok := settings[includeKey]; ok {
+		return ferrors.NewInvalidArgumentError(
+			"include directive is not supported by config sources: " + name)
+	}
+
+	lc := &layeredConfig{
+		settings: settings,
+		files:    []string{name},
+		hashes:   map[string]string{name: hashData(content.Data)},
+	}
+
+	return decodeConfig(ctx, lc, config, opts)
+}
+
+// contentVersion returns the version of the content or the hash of its data.
+func contentVersion(content *SourceContent) string {
+	if content.Version != "" {
+		return content.Version
+	}
+	return hashData(content.Data)
+}
+
+// hashData returns the hex encoded SHA-256 hash of the data.
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// sourceFormat returns the config type from the extension of the path or
+// the content type, if it is one of the types supported by viper.
+func sourceFormat(p, contentType string) string {
+	ext := strings.TrimPrefix(path.Ext(p), ".")
+	for _, supported := range viper.SupportedExts {
+		if ext == supported {
+			return ext
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "yaml"):
+		return "yaml"
+	case strings.HasSuffix(mediaType, "toml"):
+		return "toml"
+	}
+
+	return ""
+}
+
+// HTTPSource reads the configuration from an HTTP(S) URL.
+//
+// It remembers the ETag of the last response and sends it with
+// If-None-Match, so polling an unchanged configuration does not download it
+// again.
+type HTTPSource struct {
+	// URL of the configuration.
+	URL string
+
+	// Header is sent with every request, e.g. Authorization.
+	Header http.Header
+
+	// Client is the HTTP client used to fetch the configuration.
+	// Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Format is the viper config type of the configuration.
+	// Defaults to the extension of the URL path or the Content-Type of the
+	// response, then yaml.
+	Format string
+
+	mu   sync.Mutex
+	last *SourceContent
+	etag string
+}
+
+// NewHTTPSource creates a new HTTPSource reading from the url.
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{URL: url}
+}
+
+// Name returns the URL of the source.
+func (s *HTTPSource) Name() string {
+	return s.URL
+}
+
+// Read fetches the configuration. If the server responds with 304 Not
+// Modified, the last content is returned.
+func (s *HTTPSource) Read(ctx context.Context) (*SourceContent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, ferrors.WithStack(err)
+	}
+
+	for key, values := range s.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	s.mu.Lock()
+	last, etag := s.last, s.etag
+	s.mu.Unlock()
+
+	if last != nil && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to fetch config")
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if last != nil {
+			return last, nil
+		}
+		return nil, ferrors.Newf("unexpected response: %s", res.Status)
+	case http.StatusNotFound:
+		return nil, ferrors.NewNotFoundError("config not found: " + s.URL)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ferrors.NewPermissionDeniedError("permission denied to config: " + s.URL)
+	default:
+		return nil, ferrors.Newf("unexpected response: %s", res.Status)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to read config")
+	}
+
+	format := s.Format
+	if format == "" {
+		var urlPath string
+		if u, err := url.Parse(s.URL); err == nil {
+			urlPath = u.Path
+		}
+		format = sourceFormat(urlPath, res.Header.Get("Content-Type"))
+	}
+
+	content := &SourceContent{
+		Data:    data,
+		Format:  format,
+		Version: res.Header.Get("ETag"),
+	}
+
+	s.mu.Lock()
+	s.last, s.etag = content, content.Version
+	s.mu.Unlock()
+
+	return content, nil
+}
+
+// NewObjectSource creates a source reading an object from a GCS or S3
+// compatible storage over HTTP, e.g. https://storage.googleapis.com or the
+// endpoint of a MinIO server. The object is addressed path-style as
+// <endpoint>/<bucket>/<object>, changes are detected with its ETag.
+//
+// The client must authenticate the requests, e.g. by signing them for S3.
+// Use http.DefaultClient for public objects.
+func NewObjectSource(endpoint, bucket, object string, client *http.Client) *HTTPSource {
+	objectPath := (&url.URL{Path: object}).EscapedPath()
+
+	base := strings.TrimRight(endpoint, "/") + "/" + url.PathEscape(bucket)
+	return &HTTPSource{
+		URL:    base + "/" + strings.TrimLeft(objectPath, "/"),
+		Client: client,
+	}
+}
+
+// NewGCSSource creates a source reading an object from Google Cloud Storage
+// with the application default credentials.
+func NewGCSSource(ctx context.Context, bucket, object string) (*HTTPSource, error) {
+	client, err := google.DefaultClient(ctx, gcsReadScope)
+	if err != nil {
+		return nil, ferrors.Wrap(err, "unable to create GCS client")
+	}
+
+	return NewObjectSource(gcsEndpoint, bucket, object, client), nil
+}
+
+// SecretSource reads a whole configuration document stored in a secret, e.g.
+// a YAML file stored in GCP Secret Manager.
+type SecretSource struct {
+	// Secret is the secret to read, e.g.
+	// projects/<project>/secrets/<name>/versions/latest
+	Secret string
+
+	// Format is the viper config type of the configuration.
+	// Defaults to yaml.
+	Format string
+
+	// Resolver fetches the secret.
+	// Defaults to GCP Secret Manager.
+	Resolver SecretResolver
+}
+
+// NewSecretSource creates a new SecretSource reading the secret from GCP
+// Secret Manager. The client is created with the options when the secret is
+// first read, close the source to close it.
+func NewSecretSource(secret string, opts ...option.ClientOption) *SecretSource {
+	return &SecretSource{
+		Secret:   secret,
+		Resolver: &gSecretResolver{clientOpts: opts},
+	}
+}
+
+// Name returns the secret reference of the source.
+func (s *SecretSource) Name() string {
+	return SchemeGSecret + "://" + s.Secret
+}
+
+// Read fetches the secret.
+func (s *SecretSource) Read(ctx context.Context) (*SourceContent, error) {
+	if s.Resolver == nil {
+		return nil, ferrors.NewInvalidArgumentError("secret source has no resolver")
+	}
+
+	data, err := s.Resolver.Resolve(ctx, s.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	format := s.Format
+	if format == "" {
+		format = defaultSourceFormat
+	}
+
+	return &SourceContent{Data: []byte(data), Format: format}, nil
+}
+
+// Close closes the resolver, if it holds resources.
+func (s *SecretSource) Close() error {
+	if c, ok := s.Resolver.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package fconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// configServer serves a configuration with an ETag, counting the downloads.
+type configServer struct {
+	mu        sync.Mutex
+	content   string
+	etag      string
+	downloads int
+}
+
+func (s *configServer) set(content, etag string) {
+	s.mu.Lock()
+	s.content, s.etag = content, etag
+	s.mu.Unlock()
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path != "/bucket/service/config.yaml" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	s.downloads++
+	w.Header().Set("ETag", s.etag)
+	_, _ = w.Write([]byte(s.content))
+}
+
+func TestLoadSource(t *testing.T) {
+	srv := &configServer{}
+	srv.set("yamlTestVar: from-http\nnested:\n  val1: ${FCONFIG_TEST_SOURCE}\n  val2: 2\n", `"v1"`)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	t.Setenv("FCONFIG_TEST_SOURCE", "expanded")
+
+	tests := []struct {
+		name    string
+		src     Source
+		want    strictConfig
+		wantErr ferrors.ErrorCode
+	}{
+		{
+			name: "http",
+			src:  NewHTTPSource(ts.URL + "/bucket/service/config.yaml"),
+			want: strictConfig{YamlTestVar: "from-http"},
+		},
+		{
+			name: "object",
+			src:  NewObjectSource(ts.URL+"/", "bucket", "service/config.yaml", ts.Client()),
+			want: strictConfig{YamlTestVar: "from-http"},
+		},
+		{
+			name:    "missing object",
+			src:     NewObjectSource(ts.URL, "bucket", "missing.yaml", nil),
+			wantErr: ferrors.NotFound,
+		},
+		{
+			name: "secret",
+			src: &SecretSource{
+				Secret: "projects/p/secrets/config/versions/latest",
+				Format: "json",
+				Resolver: NewLocalSecretResolver(map[string]string{
+					"projects/p/secrets/config": `{"yamlTestVar": "from-secret", ` +
+						`"nested": {"val1": "${FCONFIG_TEST_SOURCE}", "val2": 2}}`,
+				}),
+			},
+			want: strictConfig{YamlTestVar: "from-secret"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := strictConfig{}
+			err := LoadSource(context.Background(), tc.src, &got, WithoutDotEnv())
+
+			if tc.wantErr != 0 {
+				if ferrors.Code(err) != tc.wantErr {
+					t.Errorf("expected error code %s but, got: %+v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected error to be nil but, got error: %+v", err)
+			}
+
+			tc.want.Nested.Val1 = "expanded"
+			tc.want.Nested.Val2 = 2
+			if got != tc.want {
+				t.Errorf("expected = %+v but, got = %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPSourceETag(t *testing.T) {
+	srv := &configServer{}
+	srv.set("yamlTestVar: v1\n", `"v1"`)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	src := NewHTTPSource(ts.URL + "/bucket/service/config.yaml")
+
+	for i := 0; i < 3; i++ {
+		content, err := src.Read(context.Background())
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+		if string(content.Data) != "yamlTestVar: v1\n" || content.Version != `"v1"` ||
+			content.Format != "yaml" {
+			t.Errorf("expected the first content but, got = %+v", content)
+		}
+	}
+
+	if srv.downloads != 1 {
+		t.Errorf("expected the config to be downloaded once but, got = %d", srv.downloads)
+	}
+}
+
+func TestWatchSource(t *testing.T) {
+	srv := &configServer{}
+	srv.set("yamlTestVar: v1\n", `"v1"`)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchSource(ctx, NewHTTPSource(ts.URL+"/bucket/service/config.yaml"), 10*time.Millisecond,
+			func(cfg strictConfig, err error) {
+				if err != nil {
+					t.Errorf("expected error to be nil but, got error: %+v", err)
+					return
+				}
+				changes <- cfg.YamlTestVar
+			},
+			WithoutDotEnv(),
+		)
+	}()
+
+	if got := <-changes; got != "v1" {
+		t.Errorf("expected initial config = v1 but, got = %s", got)
+	}
+
+	srv.set("yamlTestVar: v2\n", `"v2"`)
+
+	select {
+	case got := <-changes:
+		if got != "v2" {
+			t.Errorf("expected changed config = v2 but, got = %s", got)
+		}
+	case <-ctx.Done():
+		t.Fatalf("expected the change to be detected")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled but, got = %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("expected onChange to be called only on changes but, got %d more calls", len(changes))
+	}
+}
@@ -0,0 +1,67 @@
+package fconfig
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+type strictConfig struct {
+	YamlTestVar string `mapstructure:"yamlTestVar"`
+	Nested      struct {
+		Val1 string `mapstructure:"val1"`
+		Val2 int    `mapstructure:"val2"`
+	} `mapstructure:"nested"`
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	t.Run("should ignore unknown keys by default", func(t *testing.T) {
+		err := LoadConfigWithOptions(context.Background(), "testdata/configStrict.yaml", &strictConfig{},
+			WithoutDotEnv())
+		if err != nil {
+			t.Errorf("expected error to be nil but, got error: %+v", err)
+		}
+	})
+
+	t.Run("should fail with the paths of unknown keys", func(t *testing.T) {
+		err := LoadConfigWithOptions(context.Background(), "testdata/configStrict.yaml", &strictConfig{},
+			WithoutDotEnv(),
+			WithStrict(),
+		)
+		if err == nil {
+			t.Errorf("expected error but got nil")
+			return
+		}
+
+		if ferrors.Code(err) != ferrors.InvalidArgument {
+			t.Errorf("expected error code = %s but, got = %s", ferrors.InvalidArgument, ferrors.Code(err))
+		}
+
+		for _, key := range []string{"yamltestvra", "nested.val4"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected error to contain %s but, got: %v", key, err)
+			}
+		}
+	})
+
+	t.Run("should warn about unset fields", func(t *testing.T) {
+		report := &Report{}
+		err := LoadConfigWithOptions(context.Background(), "testdata/configStrict.yaml", &strictConfig{},
+			WithoutDotEnv(),
+			WithUnsetWarnings(),
+			WithReport(report),
+		)
+		if err != nil {
+			t.Errorf("expected error to be nil but, got error: %+v", err)
+			return
+		}
+
+		want := []string{"config field nested.val2 is not set by any source"}
+		if !reflect.DeepEqual(report.Warnings, want) {
+			t.Errorf("expected warnings = %v but, got = %v", want, report.Warnings)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package fconfig
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Validate validates the config using the `validate` and `fconfig` struct
+// tags. Both tags accept a comma separated list of rules.
+//
+// Example:
+//
+//	type Config struct {
+//		DBUri   string        `mapstructure:"db_uri" validate:"required,url"`
+//		Env     string        `mapstructure:"env" validate:"oneof=dev staging prod"`
+//		Workers int           `mapstructure:"workers" validate:"min=1,max=64"`
+//		Timeout time.Duration `mapstructure:"timeout" fconfig:"required"`
+//	}
+//
+// The supported rules are:
+//   - required: value must not be the zero value.
+//   - url: value must be an absolute URL.
+//   - min=N, max=N: numbers must be within the bound, strings, slices and maps
+//     must have the length within the bound. Durations accept N as duration
+//     e.g. min=1s.
+//   - len=N: strings, slices and maps must have exactly N length.
+//   - oneof=a b c: value must be one of the space separated values.
+//
+// Only these rules are supported. Other rules of the `validate` tag are
+// ignored, so the tag can be shared with other validators, e.g. gte=1 is not
+// checked. Unknown rules of the `fconfig` tag, e.g. a misspelled `requird`,
+// are reported as violations.
+//
+// Zero values are validated as well, e.g. `min=1` rejects 0, as the JSON Schema
+// of the config does. Empty URLs and nil pointers are accepted unless they are
+// required. Fields inside a nil struct pointer are skipped, so optional
+// sections can be declared as pointers.
+//
+// It returns an InvalidArgument error with one field per failing config path.
+func Validate(config interface{}) error {
+	root := reflect.ValueOf(config)
+	if root.Kind() == reflect.Ptr && root.IsNil() {
+		return ferrors.NewInvalidArgumentError("config must not be nil")
+	}
+
+	var violations []ferrors.Field
+
+	for _, f := range structFields(root.Type()) {
+		for _, rule := range unknownRules(f.StructField) {
+			violations = append(violations, ferrors.Field{
+				Name:        f.Path,
+				Description: "unknown validation rule: " + rule,
+			})
+		}
+
+		rules := fieldRules(f.StructField)
+		if len(rules) == 0 {
+			continue
+		}
+
+		v, ok := fieldValue(root, f)
+		if !ok {
+			// parent struct pointer is nil, the whole section is optional.
+			continue
+		}
+
+		for _, rule := range rules {
+			err := validateRule(v, rule)
+			if err != "" {
+				violations = append(violations, ferrors.Field{
+					Name:        f.Path,
+					Description: err,
+				})
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return ferrors.NewInvalidArgumentError("invalid configuration", violations...)
+	}
+
+	return nil
+}
+
+// fieldRules returns the validation rules of the field from `validate` and
+// `fconfig` tags.
+func fieldRules(sf reflect.StructField) []string {
+	var rules []string
+
+	for _, tag := range []string{"validate", "fconfig"} {
+		for _, rule := range strings.Split(sf.Tag.Get(tag), ",") {
+			rule = strings.TrimSpace(rule)
+			if isValidationRule(rule) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	return rules
+}
+
+// unknownRules returns the rules of the `fconfig` tag which are not
+// supported.
+func unknownRules(sf reflect.StructField) []string {
+	var rules []string
+
+	for _, rule := range strings.Split(sf.Tag.Get("fconfig"), ",") {
+		rule = strings.TrimSpace(rule)
+		if rule != "" && !isValidationRule(rule) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// isValidationRule reports whether rule is a supported validation rule.
+func isValidationRule(rule string) bool {
+	name := strings.SplitN(rule, "=", 2)[0]
+	switch name {
+	case "required", "url", "min", "max", "len", "oneof":
+		return true
+	}
+
+	return false
+}
+
+// validateRule validates v against the rule.
+// It returns the description of the violation, or empty string if valid.
+func validateRule(v reflect.Value, rule string) string {
+	name, param := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, param = rule[:i], rule[i+1:]
+	}
+
+	if name == "required" {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+
+	// a nil pointer is an optional value which is not set.
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return ""
+	}
+
+	v = reflect.Indirect(v)
+
+	switch name {
+	case "url":
+		// an empty URL is allowed unless it is required.
+		if v.IsZero() {
+			return ""
+		}
+
+		u, err := url.Parse(textValue(v))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid URL"
+		}
+
+	case "min", "max", "len":
+		return validateBound(v, name, param)
+
+	case "oneof":
+		val := textValue(v)
+		for _, allowed := range strings.Fields(param) {
+			if val == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", param)
+	}
+
+	return ""
+}
+
+// validateBound validates min, max and len rules.
+func validateBound(v reflect.Value, name, param string) string {
+	var got, bound float64
+	var err error
+
+	what := "value"
+
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(param)
+		got, bound = float64(v.Int()), float64(d)
+
+	case v.Kind() == reflect.String, v.Kind() == reflect.Slice,
+		v.Kind() == reflect.Map, v.Kind() == reflect.Array:
+		what = "length"
+		got = float64(v.Len())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		got = float64(v.Int())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uintptr:
+		got = float64(v.Uint())
+		bound, err = strconv.ParseFloat(param, 64)
+
+	case v.Kind() == reflect.Float32, v.Kind() == reflect.Float64:
+		got = v.Float()
+		bound, err = strconv.ParseFloat(param, 64)
+
+	default:
+		return fmt.Sprintf("%s is not supported for %s", name, v.Type())
+	}
+
+	if err != nil {
+		return fmt.Sprintf("invalid %s parameter: %s", name, param)
+	}
+
+	switch {
+	case name == "min" && got < bound:
+		return fmt.Sprintf("%s must be at least %s", what, param)
+	case name == "max" && got > bound:
+		return fmt.Sprintf("%s must be at most %s", what, param)
+	case name == "len" && got != bound:
+		return fmt.Sprintf("%s must be exactly %s", what, param)
+	}
+
+	return ""
+}
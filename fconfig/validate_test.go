@@ -0,0 +1,173 @@
+package fconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+type validateNested struct {
+	Val1 string `mapstructure:"val1" validate:"required"`
+	Val2 int    `mapstructure:"val2" validate:"min=1,max=10"`
+}
+
+type validateConfig struct {
+	DBUri   string          `mapstructure:"db_uri" validate:"required,url"`
+	Env     string          `mapstructure:"env" validate:"oneof=dev staging prod"`
+	Name    string          `mapstructure:"name" fconfig:"required" validate:"len=4"`
+	Timeout time.Duration   `mapstructure:"timeout" validate:"min=1s"`
+	Hosts   []string        `mapstructure:"hosts" validate:"max=2"`
+	Nested  validateNested  `mapstructure:"nested"`
+	Ptr     *validateNested `mapstructure:"ptr"`
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		config     *validateConfig
+		wantFields []string
+	}{
+		{
+			name: "should pass valid config",
+			config: &validateConfig{
+				DBUri:   "postgres://user@localhost:5432/db",
+				Env:     "prod",
+				Name:    "test",
+				Timeout: 2 * time.Second,
+				Hosts:   []string{"a", "b"},
+				Nested:  validateNested{Val1: "x", Val2: 3},
+			},
+		},
+		{
+			name:   "should list every failing path",
+			config: &validateConfig{},
+			wantFields: []string{
+				"db_uri",
+				"env",
+				"name",
+				"name",
+				"timeout",
+				"nested.val1",
+				"nested.val2",
+			},
+		},
+		{
+			name: "should validate values against rules",
+			config: &validateConfig{
+				DBUri:   "not a url",
+				Env:     "qa",
+				Name:    "too long",
+				Timeout: time.Millisecond,
+				Hosts:   []string{"a", "b", "c"},
+				Nested:  validateNested{Val1: "x", Val2: 11},
+				Ptr:     &validateNested{Val2: 1},
+			},
+			wantFields: []string{
+				"db_uri",
+				"env",
+				"name",
+				"timeout",
+				"hosts",
+				"nested.val2",
+				"ptr.val1",
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.config)
+			if len(tc.wantFields) == 0 {
+				if err != nil {
+					t.Errorf("expected error to be nil but, got error: %+v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Errorf("expected error but got nil")
+				return
+			}
+
+			if ferrors.Code(err) != ferrors.InvalidArgument {
+				t.Errorf("expected error code = %s but, got = %s", ferrors.InvalidArgument, ferrors.Code(err))
+				return
+			}
+
+			st, _ := status.FromError(err)
+			var got []string
+			for _, detail := range st.Details() {
+				br, ok := detail.(*errdetails.BadRequest)
+				if !ok {
+					continue
+				}
+				for _, v := range br.FieldViolations {
+					got = append(got, v.Field)
+				}
+			}
+
+			if len(got) != len(tc.wantFields) {
+				t.Errorf("expected fields = %v but, got = %v", tc.wantFields, got)
+				return
+			}
+			for i := range got {
+				if got[i] != tc.wantFields[i] {
+					t.Errorf("expected fields = %v but, got = %v", tc.wantFields, got)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAgreesWithSchema(t *testing.T) {
+	type workersConfig struct {
+		Workers int `mapstructure:"workers" validate:"min=1"`
+	}
+
+	settings := map[string]interface{}{"workers": 0}
+
+	schema, err := Schema(&workersConfig{})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	schemaErr := schema.ValidateSettings(settings)
+	validateErr := Validate(&workersConfig{Workers: 0})
+
+	if schemaErr == nil || validateErr == nil {
+		t.Errorf("expected both schema and validate to reject workers = 0 but, got = %v and %v",
+			schemaErr, validateErr)
+	}
+}
+
+func TestValidateUnknownRules(t *testing.T) {
+	type unknownRulesConfig struct {
+		Name    string `mapstructure:"name" fconfig:"requird"`
+		Workers int    `mapstructure:"workers" validate:"required,gte=1"`
+	}
+
+	err := Validate(&unknownRulesConfig{Name: "name", Workers: 1})
+	if ferrors.Code(err) != ferrors.InvalidArgument {
+		t.Fatalf("expected InvalidArgument error but, got = %v", err)
+	}
+
+	st, _ := status.FromError(err)
+	var got []string
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				got = append(got, v.Field+": "+v.Description)
+			}
+		}
+	}
+
+	want := "name: unknown validation rule: requird"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected violations = [%s] but, got = %v", want, got)
+	}
+}
@@ -0,0 +1,190 @@
+package fconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// VaultResolver resolves `vault://` references from HashiCorp Vault KV
+// secrets engine over its HTTP API.
+//
+// The expected formats are:
+// - vault://<mount>/data/<path>#<key> for KV version 2
+// - vault://<mount>/<path>#<key> for KV version 1
+//
+// The key can be omitted if the secret holds exactly one key.
+type VaultResolver struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200
+	// Defaults to VAULT_ADDR environment variable.
+	Address string
+
+	// Token used to authenticate with Vault.
+	// Defaults to VAULT_TOKEN environment variable.
+	Token string
+
+	// Namespace is the Vault Enterprise namespace.
+	// Defaults to VAULT_NAMESPACE environment variable.
+	Namespace string
+
+	// Client is the HTTP client used to call Vault.
+	// Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// lookupEnv reads the defaults from the environment, so the resolver
+	// registered while loading sees WithLookupEnv and the .env files.
+	// Defaults to os.LookupEnv.
+	lookupEnv LookupEnvFunc
+}
+
+// NewVaultResolver creates a new VaultResolver.
+// If the address, token or namespace is not set, it is read from the
+// VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables at the
+// time of resolving.
+func NewVaultResolver() *VaultResolver {
+	return &VaultResolver{}
+}
+
+// getenv returns the value of the environment variable, or empty string if it
+// is not set.
+func (r *VaultResolver) getenv(key string) string {
+	lookupEnv := r.lookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	val, _ := lookupEnv(key)
+	return val
+}
+
+// vaultResponse is the response of reading a secret from Vault.
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// Resolve fetches the secret from Vault.
+func (r *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secret, _, err := r.ResolveVersion(ctx, ref)
+	return secret, err
+}
+
+// ResolveVersion fetches the secret from Vault along with its version.
+// The version is known only for KV version 2 secrets.
+func (r *VaultResolver) ResolveVersion(ctx context.Context, ref string) (string, string, error) {
+	path, key := ref, ""
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		path, key = ref[:i], ref[i+1:]
+	}
+
+	address := r.Address
+	if address == "" {
+		address = r.getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return "", "", ferrors.New("vault address is not set, set VAULT_ADDR")
+	}
+
+	token := r.Token
+	if token == "" {
+		token = r.getenv("VAULT_TOKEN")
+	}
+
+	namespace := r.Namespace
+	if namespace == "" {
+		namespace = r.getenv("VAULT_NAMESPACE")
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := strings.TrimRight(address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", ferrors.WithStack(err)
+	}
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", "", ferrors.Wrap(err, "unable to call vault")
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", "", ferrors.NewNotFoundError("vault secret not found: " + path)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", "", ferrors.NewPermissionDeniedError("permission denied to vault secret: " + path)
+	default:
+		return "", "", ferrors.Newf("unexpected response from vault: %s", res.Status)
+	}
+
+	var body vaultResponse
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return "", "", ferrors.Wrap(err, "unable to decode vault response")
+	}
+
+	data := body.Data
+	version := ""
+	// KV version 2 nests the secret data inside data.data.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if metadata, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+			version = vaultVersion(metadata)
+		}
+	}
+
+	if key == "" {
+		if len(data) != 1 {
+			return "", "", ferrors.Newf("vault secret %s has %d keys, specify one with #<key>",
+				path, len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+
+	val, ok := data[key]
+	if !ok {
+		return "", "", ferrors.NewNotFoundError(
+			fmt.Sprintf("key %q not found in vault secret: %s", key, path))
+	}
+
+	if s, ok := val.(string); ok {
+		return s, version, nil
+	}
+
+	return fmt.Sprint(val), version, nil
+}
+
+// vaultVersion returns the version from the metadata of a KV version 2
+// secret.
+func vaultVersion(metadata interface{}) string {
+	m, ok := metadata.(map[string]interface{})
+	if !ok || m["version"] == nil {
+		return ""
+	}
+
+	// JSON numbers are decoded as float64.
+	if n, ok := m["version"].(float64); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(m["version"])
+}
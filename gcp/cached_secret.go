@@ -0,0 +1,331 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of CachedSecretClient.
+const (
+	defaultCacheTTL         = 5 * time.Minute
+	defaultNegativeCacheTTL = 30 * time.Second
+	defaultRefreshTimeout   = 30 * time.Second
+)
+
+// CacheMetrics are hooks called on the events of a CachedSecretClient, e.g.
+// to export them as metrics. Nil hooks are not called.
+//
+// The hooks are called synchronously, they must not block.
+type CacheMetrics struct {
+	// Hit is called when a secret is served from the cache, including the
+	// cached NotFound errors.
+	Hit func(name string)
+
+	// Miss is called when a secret is not cached or it has expired, before
+	// fetching it.
+	Miss func(name string)
+
+	// Refresh is called once a secret is refreshed in the background, with the
+	// error if it failed.
+	Refresh func(name string, err error)
+}
+
+// CacheOption configures a CachedSecretClient.
+type CacheOption func(*cacheOptions)
+
+// cacheOptions configures a CachedSecretClient.
+// NOTE: Don't use it directly.
+type cacheOptions struct {
+	ttl            time.Duration
+	negativeTTL    time.Duration
+	refreshAhead   time.Duration
+	refreshTimeout time.Duration
+	metrics        CacheMetrics
+}
+
+// WithCacheTTL configures how long the secrets are cached. Defaults to 5m.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithNegativeCacheTTL configures how long NotFound errors are cached, so
+// missing secrets are not fetched on every call. Defaults to 30s, zero
+// disables caching the errors.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithRefreshAhead refreshes a secret in the background when it is accessed
+// within the given duration before it expires, while the cached value is
+// still served. So frequently accessed secrets never expire on the hot path.
+// Defaults to a tenth of the TTL, zero disables refreshing.
+func WithRefreshAhead(window time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.refreshAhead = window
+	}
+}
+
+// WithRefreshTimeout configures the maximum duration of a fetch, which is
+// shared by the concurrent callers, and of a background refresh.
+// Defaults to 30s.
+func WithRefreshTimeout(timeout time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.refreshTimeout = timeout
+	}
+}
+
+// WithCacheMetrics configures the hooks called on cache events.
+func WithCacheMetrics(metrics CacheMetrics) CacheOption {
+	return func(o *cacheOptions) {
+		o.metrics = metrics
+	}
+}
+
+func buildCacheOptions(opts ...CacheOption) *cacheOptions {
+	o := &cacheOptions{
+		ttl:            defaultCacheTTL,
+		negativeTTL:    defaultNegativeCacheTTL,
+		refreshAhead:   -1,
+		refreshTimeout: defaultRefreshTimeout,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	if o.refreshAhead < 0 {
+		o.refreshAhead = o.ttl / 10
+	}
+
+	return o
+}
+
+// CachedSecretClient caches the secrets fetched with a SecretClient, for hot
+// paths which need secrets repeatedly.
+//
+// Concurrent fetches of the same secret are deduplicated into a single call,
+// NotFound errors are cached for a shorter duration and the secrets accessed
+// shortly before they expire are refreshed in the background.
+//
+// It is safe for concurrent use.
+type CachedSecretClient struct {
+	// fetch fetches a secret, it is replaced in tests.
+	fetch func(ctx context.Context, name string, opts ...CallOption) (string, error)
+
+	opts *cacheOptions
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*secretEntry
+	flights map[string]*secretFlight
+}
+
+// secretEntry is a cached secret or NotFound error.
+type secretEntry struct {
+	value      string
+	err        error
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// secretFlight is an in-flight fetch of a secret shared by concurrent callers.
+type secretFlight struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// NewCachedSecretClient creates a CachedSecretClient fetching the secrets
+// with the client. The client is owned by the caller, it must be closed once
+// the cached client is no longer used.
+//
+// Example:
+//
+//	cached := gcp.NewCachedSecretClient(client, gcp.WithCacheTTL(time.Minute))
+//	password, err := cached.GetSecretContext(ctx, "projects/p/secrets/db-password")
+func NewCachedSecretClient(client *SecretClient, opts ...CacheOption) *CachedSecretClient {
+	return &CachedSecretClient{
+		fetch:   client.GetSecretContext,
+		opts:    buildCacheOptions(opts...),
+		now:     time.Now,
+		entries: map[string]*secretEntry{},
+		flights: map[string]*secretFlight{},
+	}
+}
+
+// GetSecret returns the secret from the cache or fetches it, see
+// SecretClient.GetSecret.
+func (c *CachedSecretClient) GetSecret(name string) (string, error) {
+	return c.GetSecretContext(context.Background(), name)
+}
+
+// GetSecretContext is same as GetSecret but it accepts a context and call
+// options. The options are used only if the secret is fetched.
+func (c *CachedSecretClient) GetSecretContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) (string, error) {
+	key := cacheKey(name)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && c.now().Before(entry.expiresAt) {
+		refresh := entry.err == nil && !entry.refreshing && c.opts.refreshAhead > 0 &&
+			entry.expiresAt.Sub(c.now()) <= c.opts.refreshAhead
+		if refresh {
+			entry.refreshing = true
+		}
+		c.mu.Unlock()
+
+		c.hit(name)
+		if refresh {
+			go c.refresh(key, name, opts)
+		}
+
+		return entry.value, entry.err
+	}
+	c.mu.Unlock()
+
+	c.miss(name)
+	return c.load(ctx, key, name, opts)
+}
+
+// Invalidate removes the secret from the cache, e.g. after it is rotated.
+func (c *CachedSecretClient) Invalidate(name string) {
+	c.mu.Lock()
+	delete(c.entries, cacheKey(name))
+	c.mu.Unlock()
+}
+
+// Purge removes all the secrets from the cache.
+func (c *CachedSecretClient) Purge() {
+	c.mu.Lock()
+	c.entries = map[string]*secretEntry{}
+	c.mu.Unlock()
+}
+
+// load fetches the secret, sharing the fetch with the concurrent callers, and
+// caches the result.
+//
+// The fetch does not use the context of the callers, so a caller giving up
+// does not fail the others; it is bounded by the refresh timeout instead.
+func (c *CachedSecretClient) load(
+	ctx context.Context,
+	key, name string,
+	opts []CallOption,
+) (string, error) {
+	c.mu.Lock()
+	flight, ok := c.flights[key]
+	if !ok {
+		flight = &secretFlight{done: make(chan struct{})}
+		c.flights[key] = flight
+		go c.fly(key, name, opts, flight)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", ferrors.WithStack(ctx.Err())
+	case <-flight.done:
+		return flight.value, flight.err
+	}
+}
+
+// fly fetches the secret of the flight and caches the result.
+// A panic of the fetch is returned as an Internal error to the callers.
+func (c *CachedSecretClient) fly(key, name string, opts []CallOption, flight *secretFlight) {
+	defer func() {
+		if r := recover(); r != nil {
+			flight.value, flight.err = "", ferrors.NewInternalError(
+				fmt.Sprintf("fetching secret %s panicked: %v", name, r))
+		}
+
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(flight.done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.refreshTimeout)
+	defer cancel()
+
+	flight.value, flight.err = c.fetch(ctx, name, opts...)
+	c.store(key, flight.value, flight.err)
+}
+
+// refresh fetches the secret in the background and replaces the cached one.
+// The cached secret is kept if the refresh fails.
+func (c *CachedSecretClient) refresh(key, name string, opts []CallOption) {
+	_, err := c.load(context.Background(), key, name, opts)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		entry.refreshing = false
+	}
+	c.mu.Unlock()
+
+	if c.opts.metrics.Refresh != nil {
+		c.opts.metrics.Refresh(name, err)
+	}
+}
+
+// store caches the secret or the NotFound error.
+func (c *CachedSecretClient) store(key, value string, err error) {
+	ttl := c.opts.ttl
+	if err != nil {
+		if !isNotFound(err) || c.opts.negativeTTL <= 0 {
+			return
+		}
+		ttl = c.opts.negativeTTL
+	}
+
+	c.mu.Lock()
+	c.entries[key] = &secretEntry{
+		value:     value,
+		err:       err,
+		expiresAt: c.now().Add(ttl),
+	}
+	c.mu.Unlock()
+}
+
+func (c *CachedSecretClient) hit(name string) {
+	if c.opts.metrics.Hit != nil {
+		c.opts.metrics.Hit(name)
+	}
+}
+
+func (c *CachedSecretClient) miss(name string) {
+	if c.opts.metrics.Miss != nil {
+		c.opts.metrics.Miss(name)
+	}
+}
+
+// cacheKey returns the name of the secret version, so a secret and its
+// latest version share the same entry.
+func cacheKey(name string) string {
+	if len(strings.Split(name, "/")) == minSecretPathLength {
+		return name + "/versions/latest"
+	}
+	return name
+}
+
+// isNotFound reports whether err is a NotFound error.
+func isNotFound(err error) bool {
+	return ferrors.Code(err) == ferrors.NotFound || status.Code(err) == codes.NotFound
+}
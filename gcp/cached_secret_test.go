@@ -0,0 +1,266 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock is a clock moved manually.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestCachedClient(
+	fetch func(ctx context.Context, name string, opts ...CallOption) (string, error),
+	opts ...CacheOption,
+) (*CachedSecretClient, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	c := NewCachedSecretClient(&SecretClient{}, opts...)
+	c.fetch = fetch
+	c.now = clock.Now
+
+	return c, clock
+}
+
+func TestCachedSecretClient(t *testing.T) {
+	const name = "projects/p/secrets/s"
+
+	var fetches int32
+	fetch := func(_ context.Context, name string, _ ...CallOption) (string, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		if name == "projects/p/secrets/missing" {
+			return "", status.Error(codes.NotFound, "not found")
+		}
+		return "v" + string(rune('0'+n)), nil
+	}
+
+	var hits, misses int32
+	c, clock := newTestCachedClient(fetch,
+		WithCacheTTL(time.Minute),
+		WithRefreshAhead(0),
+		WithCacheMetrics(CacheMetrics{
+			Hit:  func(string) { atomic.AddInt32(&hits, 1) },
+			Miss: func(string) { atomic.AddInt32(&misses, 1) },
+		}),
+	)
+
+	for i := 0; i < 3; i++ {
+		got, err := c.GetSecret(name)
+		if err != nil || got != "v1" {
+			t.Errorf("expected = v1 but, got = %s, error: %+v", got, err)
+		}
+	}
+
+	// the latest version shares the entry of the secret.
+	if got, _ := c.GetSecret(name + "/versions/latest"); got != "v1" {
+		t.Errorf("expected = v1 but, got = %s", got)
+	}
+
+	if fetches != 1 || hits != 3 || misses != 1 {
+		t.Errorf("expected 1 fetch, 3 hits and 1 miss but, got %d, %d and %d", fetches, hits, misses)
+	}
+
+	clock.Add(time.Minute)
+	if got, _ := c.GetSecret(name); got != "v2" {
+		t.Errorf("expected the expired secret to be fetched again but, got = %s", got)
+	}
+
+	c.Invalidate(name)
+	if got, _ := c.GetSecret(name); got != "v3" {
+		t.Errorf("expected the invalidated secret to be fetched again but, got = %s", got)
+	}
+
+	// NotFound errors are cached for the negative TTL.
+	for i := 0; i < 2; i++ {
+		_, err := c.GetSecret("projects/p/secrets/missing")
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound error but, got = %v", err)
+		}
+	}
+	if fetches != 4 {
+		t.Errorf("expected the NotFound error to be cached but, got %d fetches", fetches)
+	}
+
+	clock.Add(defaultNegativeCacheTTL)
+	_, _ = c.GetSecret("projects/p/secrets/missing")
+	if fetches != 5 {
+		t.Errorf("expected the NotFound error to expire but, got %d fetches", fetches)
+	}
+}
+
+func TestCachedSecretClientDeduplicatesFetches(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(context.Context, string, ...CallOption) (string, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "value", nil
+	}
+
+	c, _ := newTestCachedClient(fetch)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := c.GetSecret("projects/p/secrets/s"); err != nil || got != "value" {
+				t.Errorf("expected = value but, got = %s, error: %+v", got, err)
+			}
+		}()
+	}
+
+	// let the callers join the in-flight fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("expected concurrent fetches to be deduplicated but, got %d fetches", fetches)
+	}
+}
+
+func TestCachedSecretClientFirstCallerCancels(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	fetch := func(ctx context.Context, _ string, _ ...CallOption) (string, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-release:
+			return "value", nil
+		}
+	}
+
+	c, _ := newTestCachedClient(fetch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetSecretContext(ctx, "projects/p/secrets/s")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan string, 1)
+	go func() {
+		got, err := c.GetSecret("projects/p/secrets/s")
+		if err != nil {
+			t.Errorf("expected error to be nil but, got error: %+v", err)
+		}
+		second <- got
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but, got = %v", err)
+	}
+
+	// let the second caller join the in-flight fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if got := <-second; got != "value" {
+		t.Errorf("expected = value but, got = %s", got)
+	}
+}
+
+func TestCachedSecretClientFetchPanics(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func(context.Context, string, ...CallOption) (string, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			<-release
+			panic("boom")
+		}
+		return "value", nil
+	}
+
+	c, _ := newTestCachedClient(fetch)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetSecret("projects/p/secrets/s")
+			if ferrors.Code(err) != ferrors.Internal {
+				t.Errorf("expected Internal error but, got = %v", err)
+			}
+		}()
+	}
+
+	// let the callers join the in-flight fetch.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// the failed fetch is not cached.
+	if got, err := c.GetSecret("projects/p/secrets/s"); err != nil || got != "value" {
+		t.Errorf("expected = value but, got = %s, error: %+v", got, err)
+	}
+}
+
+func TestCachedSecretClientRefreshesAhead(t *testing.T) {
+	var fetches int32
+	fetch := func(context.Context, string, ...CallOption) (string, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		return "v" + string(rune('0'+n)), nil
+	}
+
+	refreshed := make(chan error, 1)
+	c, clock := newTestCachedClient(fetch,
+		WithCacheTTL(time.Minute),
+		WithRefreshAhead(10*time.Second),
+		WithCacheMetrics(CacheMetrics{
+			Refresh: func(_ string, err error) { refreshed <- err },
+		}),
+	)
+
+	if got, _ := c.GetSecret("projects/p/secrets/s"); got != "v1" {
+		t.Errorf("expected = v1 but, got = %s", got)
+	}
+
+	// within the refresh window the cached secret is served and refreshed in
+	// the background.
+	clock.Add(55 * time.Second)
+	if got, _ := c.GetSecret("projects/p/secrets/s"); got != "v1" {
+		t.Errorf("expected the cached secret while refreshing but, got = %s", got)
+	}
+
+	select {
+	case err := <-refreshed:
+		if err != nil {
+			t.Fatalf("expected error to be nil but, got error: %+v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the secret to be refreshed")
+	}
+
+	clock.Add(10 * time.Second)
+	if got, _ := c.GetSecret("projects/p/secrets/s"); got != "v2" {
+		t.Errorf("expected the refreshed secret but, got = %s", got)
+	}
+}
@@ -0,0 +1,152 @@
+package gcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+)
+
+// Defaults of RetryPolicy.
+const (
+	defaultRetryInitial    = 100 * time.Millisecond
+	defaultRetryMax        = 10 * time.Second
+	defaultRetryMultiplier = 2
+)
+
+// defaultRetryCodes are the gRPC codes retried by default.
+var defaultRetryCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+
+// CallOption configures a call to GCP Secret Manager.
+type CallOption func(*callOptions)
+
+// callOptions configures a call to GCP Secret Manager.
+// NOTE: Don't use it directly.
+type callOptions struct {
+	// timeout is the maximum duration of the call, including the retries.
+	// Zero means no timeout other than the context.
+	timeout time.Duration
+
+	// retry overrides the default retry policy of the client, if set.
+	retry *RetryPolicy
+}
+
+// RetryPolicy configures how failed calls are retried with exponential
+// backoff.
+type RetryPolicy struct {
+	// Codes are the gRPC codes of the errors to retry.
+	// Defaults to Unavailable and ResourceExhausted.
+	Codes []codes.Code
+
+	// MaxAttempts is the maximum number of attempts, including the first
+	// one. Zero means the call is retried until the context is done.
+	MaxAttempts int
+
+	// Initial is the backoff before the first retry. Defaults to 100ms.
+	Initial time.Duration
+
+	// Max is the maximum backoff between retries. Defaults to 10s.
+	Max time.Duration
+
+	// Multiplier is the factor by which the backoff increases after each
+	// retry. Defaults to 2.
+	Multiplier float64
+}
+
+// WithCallTimeout configures the maximum duration of the call, including the
+// retries.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry configures how the call is retried, instead of the default retry
+// policy of GCP Secret Manager client.
+func WithRetry(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retry = &policy
+	}
+}
+
+// WithoutRetry disables retrying the call.
+func WithoutRetry() CallOption {
+	return WithRetry(RetryPolicy{MaxAttempts: 1})
+}
+
+func buildCallOptions(opts ...CallOption) *callOptions {
+	o := &callOptions{}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// apply applies the timeout to the context and returns the gax options of
+// the call.
+func (o *callOptions) apply(
+	ctx context.Context,
+) (context.Context, context.CancelFunc, []gax.CallOption) {
+	cancel := context.CancelFunc(func() {})
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+	}
+
+	if o.retry == nil {
+		return ctx, cancel, nil
+	}
+
+	policy := *o.retry
+	return ctx, cancel, []gax.CallOption{gax.WithRetry(policy.retryer)}
+}
+
+// retryer returns a new retryer of the policy, for a single call.
+func (p RetryPolicy) retryer() gax.Retryer {
+	retryCodes := p.Codes
+	if len(retryCodes) == 0 {
+		retryCodes = defaultRetryCodes
+	}
+
+	backoff := gax.Backoff{
+		Initial:    p.Initial,
+		Max:        p.Max,
+		Multiplier: p.Multiplier,
+	}
+	if backoff.Initial <= 0 {
+		backoff.Initial = defaultRetryInitial
+	}
+	if backoff.Max <= 0 {
+		backoff.Max = defaultRetryMax
+	}
+	if backoff.Multiplier < 1 {
+		backoff.Multiplier = defaultRetryMultiplier
+	}
+
+	return &limitedRetryer{
+		retryer:     gax.OnCodes(retryCodes, backoff),
+		maxAttempts: p.MaxAttempts,
+		attempts:    1,
+	}
+}
+
+// limitedRetryer stops retrying after the maximum number of attempts.
+type limitedRetryer struct {
+	retryer     gax.Retryer
+	maxAttempts int
+	attempts    int
+}
+
+// Retry reports whether the call should be retried and how long to wait.
+func (r *limitedRetryer) Retry(err error) (time.Duration, bool) {
+	if r.maxAttempts > 0 && r.attempts >= r.maxAttempts {
+		return 0, false
+	}
+	r.attempts++
+
+	return r.retryer.Retry(err)
+}
@@ -0,0 +1,87 @@
+package gcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	notFound := status.Error(codes.NotFound, "not found")
+
+	testCases := []struct {
+		name        string
+		policy      RetryPolicy
+		err         error
+		wantRetries int
+	}{
+		{
+			name:        "should retry until max attempts",
+			policy:      RetryPolicy{MaxAttempts: 3},
+			err:         unavailable,
+			wantRetries: 2,
+		},
+		{
+			name:        "should not retry without retry",
+			policy:      RetryPolicy{MaxAttempts: 1},
+			err:         unavailable,
+			wantRetries: 0,
+		},
+		{
+			name:        "should not retry other codes",
+			policy:      RetryPolicy{MaxAttempts: 3},
+			err:         notFound,
+			wantRetries: 0,
+		},
+		{
+			name:        "should retry the configured codes",
+			policy:      RetryPolicy{MaxAttempts: 2, Codes: []codes.Code{codes.NotFound}},
+			err:         notFound,
+			wantRetries: 1,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			retryer := tc.policy.retryer()
+
+			retries := 0
+			for ; retries < 10; retries++ {
+				backoff, ok := retryer.Retry(tc.err)
+				if !ok {
+					break
+				}
+				if backoff > defaultRetryMax {
+					t.Errorf("expected backoff to be at most %s but, got = %s", defaultRetryMax, backoff)
+				}
+			}
+
+			if retries != tc.wantRetries {
+				t.Errorf("expected retries = %d but, got = %d", tc.wantRetries, retries)
+			}
+		})
+	}
+}
+
+func TestCallOptions(t *testing.T) {
+	c := (&SecretClient{ctx: context.Background()}).WithCallOptions(WithCallTimeout(time.Hour))
+
+	callOpts := []CallOption{WithCallTimeout(time.Second), WithoutRetry()}
+	ctx, cancel, gaxOpts := c.call(context.Background(), callOpts)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Second {
+		t.Errorf("expected the call timeout to override the client timeout but, got deadline = %v",
+			deadline)
+	}
+
+	if len(gaxOpts) != 1 {
+		t.Errorf("expected a retry option but, got = %d options", len(gaxOpts))
+	}
+}
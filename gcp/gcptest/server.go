@@ -0,0 +1,540 @@
+// Package gcptest provides an in-memory GCP Secret Manager for tests.
+//
+// Example:
+//
+//	func TestSomething(t *testing.T) {
+//		client, server := gcptest.NewSecretClient(t)
+//		server.SetSecret("projects/p/secrets/db-password", "password")
+//
+//		password, err := client.GetSecret("projects/p/secrets/db-password")
+//		...
+//	}
+package gcptest
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/gcp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const bufSize = 1024 * 1024
+
+// crc32c is the table of the CRC32C checksums of the payloads.
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Server is an in-memory GCP Secret Manager served over gRPC.
+//
+// It implements the secrets and the versions of the API, including the
+// `latest` alias, the version states and the CRC32C checksums of the
+// payloads. The IAM methods are not implemented.
+//
+// It is safe for concurrent use.
+type Server struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+
+	listener *bufconn.Listener
+	server   *grpc.Server
+
+	mu      sync.Mutex
+	secrets map[string]*secret
+
+	// failures are the errors returned by the next calls, in order.
+	failures []error
+
+	// calls counts the calls by method name, e.g. AccessSecretVersion.
+	calls map[string]int
+}
+
+// secret is a secret with its versions, ordered by number.
+type secret struct {
+	secret   *secretmanagerpb.Secret
+	versions []*version
+}
+
+type version struct {
+	version *secretmanagerpb.SecretVersion
+	data    []byte
+}
+
+// NewServer starts an in-memory GCP Secret Manager.
+// The server must be Closed when it is done being used.
+func NewServer() *Server {
+	s := &Server{
+		listener: bufconn.Listen(bufSize),
+		secrets:  map[string]*secret{},
+		calls:    map[string]int{},
+	}
+
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(s.intercept))
+	secretmanagerpb.RegisterSecretManagerServiceServer(s.server, s)
+
+	go func() {
+		// Serve only returns once the server is stopped.
+		_ = s.server.Serve(s.listener)
+	}()
+
+	return s
+}
+
+// NewSecretClient starts a server and returns a client connected to it.
+// Both are closed once the test completes.
+func NewSecretClient(tb testing.TB) (*gcp.SecretClient, *Server) {
+	tb.Helper()
+
+	s := NewServer()
+	tb.Cleanup(s.Close)
+
+	client, err := s.Client(context.Background())
+	if err != nil {
+		tb.Fatalf("unable to create secret client: %+v", err)
+	}
+	tb.Cleanup(func() { _ = client.Close() })
+
+	return client, s
+}
+
+// Client returns a new client connected to the server.
+// The returned client must be Closed when it is done being used.
+func (s *Server) Client(ctx context.Context) (*gcp.SecretClient, error) {
+	conn, err := s.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcp.NewSecretClient(option.WithGRPCConn(conn))
+}
+
+// Dial returns a new connection to the server, e.g. to create a
+// secretmanager.Client with option.WithGRPCConn.
+func (s *Server) Dial(ctx context.Context) (*grpc.ClientConn, error) {
+	return grpc.DialContext(
+		ctx,
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.server.Stop()
+}
+
+// SetSecret creates the secret if it does not exist, and adds a version with
+// the value. It returns the name of the version.
+//
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (s *Server) SetSecret(name, value string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, ok := s.secrets[name]
+	if !ok {
+		sec = &secret{secret: &secretmanagerpb.Secret{
+			Name:       name,
+			CreateTime: timestamppb.Now(),
+		}}
+		s.secrets[name] = sec
+	}
+
+	return sec.add([]byte(value)).Name
+}
+
+// FailNext makes the next calls fail with the errors, in order, e.g. to test
+// the retries of the client.
+func (s *Server) FailNext(errs ...error) {
+	s.mu.Lock()
+	s.failures = append(s.failures, errs...)
+	s.mu.Unlock()
+}
+
+// Calls returns the number of calls of the method, including the failed
+// ones, e.g. Calls("AccessSecretVersion").
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// intercept counts the calls and returns the injected failures.
+func (s *Server) intercept(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+
+	s.mu.Lock()
+	s.calls[method]++
+	var err error
+	if len(s.failures) > 0 {
+		err, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// ListSecrets lists the secrets of the project.
+func (s *Server) ListSecrets(
+	_ context.Context,
+	req *secretmanagerpb.ListSecretsRequest,
+) (*secretmanagerpb.ListSecretsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &secretmanagerpb.ListSecretsResponse{}
+	for name, sec := range s.secrets {
+		if strings.HasPrefix(name, req.Parent+"/secrets/") {
+			res.Secrets = append(res.Secrets, clone(sec.secret))
+		}
+	}
+	sort.Slice(res.Secrets, func(i, j int) bool {
+		return res.Secrets[i].Name < res.Secrets[j].Name
+	})
+	res.TotalSize = int32(len(res.Secrets))
+
+	return res, nil
+}
+
+// CreateSecret creates a secret without versions.
+func (s *Server) CreateSecret(
+	_ context.Context,
+	req *secretmanagerpb.CreateSecretRequest,
+) (*secretmanagerpb.Secret, error) {
+	if req.SecretId == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret id is required")
+	}
+	if req.Secret.GetReplication() == nil {
+		return nil, status.Error(codes.InvalidArgument, "replication is required")
+	}
+	if req.Secret.GetRotation() != nil && len(req.Secret.GetTopics()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rotation requires topics")
+	}
+
+	name := req.Parent + "/secrets/" + req.SecretId
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.secrets[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "secret %s already exists", name)
+	}
+
+	sec := clone(req.Secret)
+	sec.Name = name
+	sec.CreateTime = timestamppb.Now()
+	s.secrets[name] = &secret{secret: sec}
+
+	return clone(sec), nil
+}
+
+// AddSecretVersion adds an enabled version to the secret.
+func (s *Server) AddSecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.AddSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	data := req.Payload.GetData()
+	if req.Payload.DataCrc32C != nil && req.Payload.GetDataCrc32C() != checksum(data) {
+		return nil, status.Error(codes.InvalidArgument, "payload checksum does not match")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.secret(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(sec.add(data)), nil
+}
+
+// GetSecret returns the secret.
+func (s *Server) GetSecret(
+	_ context.Context,
+	req *secretmanagerpb.GetSecretRequest,
+) (*secretmanagerpb.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.secret(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(sec.secret), nil
+}
+
+// UpdateSecret updates the labels, the rotation, the topics or the expiration
+// of the secret.
+func (s *Server) UpdateSecret(
+	_ context.Context,
+	req *secretmanagerpb.UpdateSecretRequest,
+) (*secretmanagerpb.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.secret(req.Secret.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	updated := clone(sec.secret)
+	for _, path := range req.UpdateMask.GetPaths() {
+		switch path {
+		case "labels":
+			updated.Labels = req.Secret.Labels
+		case "annotations":
+			updated.Annotations = req.Secret.Annotations
+		case "rotation":
+			updated.Rotation = req.Secret.Rotation
+		case "topics":
+			updated.Topics = req.Secret.Topics
+		case "ttl", "expire_time":
+			updated.Expiration = req.Secret.Expiration
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "field %s cannot be updated", path)
+		}
+	}
+
+	if updated.Rotation != nil && len(updated.Topics) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rotation requires topics")
+	}
+
+	sec.secret = updated
+	return clone(updated), nil
+}
+
+// DeleteSecret deletes the secret and its versions.
+func (s *Server) DeleteSecret(
+	_ context.Context,
+	req *secretmanagerpb.DeleteSecretRequest,
+) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.secret(req.Name); err != nil {
+		return nil, err
+	}
+	delete(s.secrets, req.Name)
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListSecretVersions lists the versions of the secret, newest first.
+func (s *Server) ListSecretVersions(
+	_ context.Context,
+	req *secretmanagerpb.ListSecretVersionsRequest,
+) (*secretmanagerpb.ListSecretVersionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sec, err := s.secret(req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &secretmanagerpb.ListSecretVersionsResponse{}
+	for i := len(sec.versions) - 1; i >= 0; i-- {
+		res.Versions = append(res.Versions, clone(sec.versions[i].version))
+	}
+	res.TotalSize = int32(len(res.Versions))
+
+	return res, nil
+}
+
+// GetSecretVersion returns the version of the secret.
+func (s *Server) GetSecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.GetSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return clone(v.version), nil
+}
+
+// AccessSecretVersion returns the payload of the enabled version.
+func (s *Server) AccessSecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.AccessSecretVersionRequest,
+) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.version.State != secretmanagerpb.SecretVersion_ENABLED {
+		return nil, status.Errorf(codes.FailedPrecondition, "secret version %s is in %s state",
+			v.version.Name, v.version.State)
+	}
+
+	crc := checksum(v.data)
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name: v.version.Name,
+		Payload: &secretmanagerpb.SecretPayload{
+			Data:       append([]byte(nil), v.data...),
+			DataCrc32C: &crc,
+		},
+	}, nil
+}
+
+// DisableSecretVersion disables the version.
+func (s *Server) DisableSecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.DisableSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	return s.setState(req.Name, secretmanagerpb.SecretVersion_DISABLED)
+}
+
+// EnableSecretVersion enables the version.
+func (s *Server) EnableSecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.EnableSecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	return s.setState(req.Name, secretmanagerpb.SecretVersion_ENABLED)
+}
+
+// DestroySecretVersion destroys the version and its payload.
+func (s *Server) DestroySecretVersion(
+	_ context.Context,
+	req *secretmanagerpb.DestroySecretVersionRequest,
+) (*secretmanagerpb.SecretVersion, error) {
+	return s.setState(req.Name, secretmanagerpb.SecretVersion_DESTROYED)
+}
+
+// setState changes the state of the version, destroyed versions cannot be
+// changed.
+func (s *Server) setState(
+	name string,
+	state secretmanagerpb.SecretVersion_State,
+) (*secretmanagerpb.SecretVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.version(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.version.State == secretmanagerpb.SecretVersion_DESTROYED {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"secret version %s is destroyed", v.version.Name)
+	}
+
+	v.version.State = state
+	if state == secretmanagerpb.SecretVersion_DESTROYED {
+		v.version.DestroyTime = timestamppb.Now()
+		v.data = nil
+	}
+
+	return clone(v.version), nil
+}
+
+// secret returns the secret by name.
+// NOTE: s.mu must be held.
+func (s *Server) secret(name string) (*secret, error) {
+	if len(strings.Split(name, "/")) != 4 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid secret name: %s", name)
+	}
+
+	sec, ok := s.secrets[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "secret %s not found", name)
+	}
+
+	return sec, nil
+}
+
+// version returns the version by name, `latest` is the newest enabled
+// version.
+// NOTE: s.mu must be held.
+func (s *Server) version(name string) (*version, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 6 || parts[4] != "versions" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid secret version name: %s", name)
+	}
+
+	sec, err := s.secret(strings.Join(parts[:4], "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if parts[5] == "latest" {
+		for i := len(sec.versions) - 1; i >= 0; i-- {
+			if sec.versions[i].version.State == secretmanagerpb.SecretVersion_ENABLED {
+				return sec.versions[i], nil
+			}
+		}
+		return nil, status.Errorf(codes.NotFound, "secret %s has no enabled version", sec.secret.Name)
+	}
+
+	n, err := strconv.Atoi(parts[5])
+	if err != nil || n < 1 || n > len(sec.versions) {
+		return nil, status.Errorf(codes.NotFound, "secret version %s not found", name)
+	}
+
+	return sec.versions[n-1], nil
+}
+
+// add adds an enabled version with the data.
+// NOTE: Server.mu must be held.
+func (sec *secret) add(data []byte) *secretmanagerpb.SecretVersion {
+	v := &version{
+		version: &secretmanagerpb.SecretVersion{
+			Name:       fmt.Sprintf("%s/versions/%d", sec.secret.Name, len(sec.versions)+1),
+			CreateTime: timestamppb.Now(),
+			State:      secretmanagerpb.SecretVersion_ENABLED,
+		},
+		data: append([]byte(nil), data...),
+	}
+	sec.versions = append(sec.versions, v)
+
+	return v.version
+}
+
+// checksum returns the CRC32C checksum of the data.
+func checksum(data []byte) int64 {
+	return int64(crc32.Checksum(data, crc32c))
+}
+
+// clone returns a deep copy of the message, so the stored messages are not
+// shared with the callers.
+func clone[M proto.Message](m M) M {
+	return proto.Clone(m).(M)
+}
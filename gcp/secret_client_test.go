@@ -0,0 +1,257 @@
+package gcp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/Flahmingo-Investments/helpers-go/gcp"
+	"github.com/Flahmingo-Investments/helpers-go/gcp/gcptest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const secretName = "projects/test/secrets/db-password"
+
+func TestSecretClientLifecycle(t *testing.T) {
+	client, server := gcptest.NewSecretClient(t)
+
+	err := client.CreateSecretWithConfig(secretName, "v1", gcp.SecretConfig{
+		Labels:   map[string]string{"team": "core"},
+		Replicas: []gcp.Replica{{Location: "us-east1"}},
+		TTL:      time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	err = client.CreateSecret(secretName, "v1")
+	if ferrors.Code(err) != ferrors.AlreadyExists {
+		t.Errorf("expected AlreadyExists error but, got = %v", err)
+	}
+
+	version, err := client.AddVersion(secretName, "v2")
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if version != secretName+"/versions/2" {
+		t.Errorf("expected version = %s/versions/2 but, got = %s", secretName, version)
+	}
+
+	assertSecret(t, client, secretName, "v2")
+
+	if err := client.DisableVersion(version); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	assertSecret(t, client, secretName, "v1")
+
+	_, err = client.GetSecret(version)
+	if ferrors.Code(err) != ferrors.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition error but, got = %v", err)
+	}
+
+	if err := client.EnableVersion(version); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	assertSecret(t, client, secretName, "v2")
+
+	if err := client.DestroyVersion(secretName + "/versions/1"); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	versions, err := client.ListSecretVersions(secretName)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if len(versions) != 2 || versions[1].State != secretmanagerpb.SecretVersion_DESTROYED {
+		t.Errorf("expected the first version to be destroyed but, got = %v", versions)
+	}
+
+	if err := client.UpdateLabels(secretName, map[string]string{"team": "platform"}); err != nil {
+		t.Errorf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if err := client.SetAnnotations(secretName, map[string]string{"owner": "core"}); err != nil {
+		t.Errorf("expected error to be nil but, got error: %+v", err)
+	}
+
+	secret := getSecret(t, server, secretName)
+	if secret.Labels["team"] != "platform" || secret.Annotations["owner"] != "core" {
+		t.Errorf("expected labels and annotations to be updated but, got = %v", secret)
+	}
+
+	err = client.UpdateRotation(secretName, &gcp.Rotation{Period: 24 * time.Hour})
+	if ferrors.Code(err) != ferrors.InvalidArgument {
+		t.Errorf("expected InvalidArgument error for rotation without topics but, got = %v", err)
+	}
+
+	if err := client.DeleteSecret(secretName); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	_, err = client.GetSecret(secretName)
+	if ferrors.Code(err) != ferrors.NotFound {
+		t.Errorf("expected NotFound error but, got = %v", err)
+	}
+
+	if server.Calls("DeleteSecret") != 1 {
+		t.Errorf("expected 1 DeleteSecret call but, got = %d", server.Calls("DeleteSecret"))
+	}
+}
+
+func TestSecretClientRetry(t *testing.T) {
+	client, server := gcptest.NewSecretClient(t)
+	server.SetSecret(secretName, "password")
+
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	server.FailNext(unavailable, unavailable)
+	retry := gcp.WithRetry(gcp.RetryPolicy{
+		MaxAttempts: 3,
+		Initial:     time.Millisecond,
+	})
+	secret, err := client.GetSecretContext(context.Background(), secretName, retry)
+	if err != nil || secret != "password" {
+		t.Errorf("expected = password but, got = %s, error: %+v", secret, err)
+	}
+	if calls := server.Calls("AccessSecretVersion"); calls != 3 {
+		t.Errorf("expected 3 calls but, got = %d", calls)
+	}
+
+	server.FailNext(unavailable)
+	_, err = client.GetSecretContext(context.Background(), secretName, gcp.WithoutRetry())
+	if ferrors.Code(err) != ferrors.Unavailable {
+		t.Errorf("expected Unavailable error but, got = %v", err)
+	}
+}
+
+func TestSecretClientWatch(t *testing.T) {
+	client, server := gcptest.NewSecretClient(t)
+	server.SetSecret(secretName, "v1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type change struct{ old, new string }
+	changes := make(chan change, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- client.Watch(ctx, secretName, func(old, new string) {
+			changes <- change{old, new}
+		}, gcp.WithWatchInterval(10*time.Millisecond))
+	}()
+
+	expectChange := func(want change) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("expected change = %v but, got = %v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected change = %v", want)
+		}
+	}
+
+	// let the watch read the initial version.
+	time.Sleep(50 * time.Millisecond)
+
+	version := server.SetSecret(secretName, "v2")
+	expectChange(change{"v1", "v2"})
+
+	if err := client.DisableVersion(version); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	expectChange(change{"v2", "v1"})
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled but, got = %v", err)
+	}
+}
+
+func TestDefaultSecretClient(t *testing.T) {
+	server := gcptest.NewServer()
+	defer server.Close()
+	server.SetSecret(secretName, "password")
+
+	conn, err := server.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	if err := gcp.SetDefaultClientOptions(option.WithGRPCConn(conn)); err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	defer gcp.CloseDefaultSecretClient()
+
+	secret, err := gcp.GetSecretByName(secretName + "/versions/latest")
+	if err != nil || secret != "password" {
+		t.Errorf("expected = password but, got = %s, error: %+v", secret, err)
+	}
+
+	versions, err := gcp.ListSecretVersions(secretName + "/versions/1")
+	if err != nil || len(versions) != 1 {
+		t.Errorf("expected 1 version but, got = %v, error: %+v", versions, err)
+	}
+
+	_, err = gcp.GetSecretByName("projects/test/secrets/missing/versions/latest")
+	if ferrors.Code(err) != ferrors.NotFound {
+		t.Errorf("expected NotFound error but, got = %v", err)
+	}
+
+	// the package-level functions share the connection of the default client.
+	client, err := gcp.DefaultSecretClient()
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if again, _ := gcp.DefaultSecretClient(); again != client {
+		t.Errorf("expected the default client to be reused")
+	}
+
+	// the client in use is not replaced under its callers.
+	err = gcp.SetDefaultClientOptions(option.WithGRPCConn(conn))
+	if ferrors.Code(err) != ferrors.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition error but, got = %v", err)
+	}
+	if _, err := client.GetSecret(secretName + "/versions/latest"); err != nil {
+		t.Errorf("expected the default client to be usable but, got error: %+v", err)
+	}
+}
+
+// getSecret returns the metadata of the secret from the server.
+func getSecret(t *testing.T, server *gcptest.Server, name string) *secretmanagerpb.Secret {
+	t.Helper()
+
+	conn, err := server.Dial(context.Background())
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	defer conn.Close()
+
+	secret, err := secretmanagerpb.NewSecretManagerServiceClient(conn).GetSecret(
+		context.Background(),
+		&secretmanagerpb.GetSecretRequest{Name: name},
+	)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+
+	return secret
+}
+
+func assertSecret(t *testing.T, client *gcp.SecretClient, name, want string) {
+	t.Helper()
+
+	got, err := client.GetSecret(name)
+	if err != nil {
+		t.Fatalf("expected error to be nil but, got error: %+v", err)
+	}
+	if got != want {
+		t.Errorf("expected = %s but, got = %s", want, got)
+	}
+}
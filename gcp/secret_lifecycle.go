@@ -0,0 +1,435 @@
+package gcp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SecretConfig configures a secret on creation.
+// The zero value creates a secret with automatic replication, as CreateSecret
+// does.
+type SecretConfig struct {
+	// Labels are the labels of the secret.
+	Labels map[string]string
+
+	// Annotations are the annotations of the secret, e.g. the state of the
+	// tools managing it. Unlike labels, they can not be used to filter secrets.
+	Annotations map[string]string
+
+	// Replicas are the locations the secret is replicated to, e.g.
+	// `us-east1`. If set, the secret is replicated to these locations only
+	// instead of being replicated automatically.
+	Replicas []Replica
+
+	// KMSKeyName is the Cloud KMS key used to encrypt the secret with
+	// automatic replication, e.g.
+	// projects/<project>/locations/global/keyRings/<ring>/cryptoKeys/<key>.
+	// Defaults to Google managed encryption.
+	KMSKeyName string
+
+	// TTL is the duration after which the secret is deleted.
+	// Only one of TTL and ExpireTime can be set.
+	TTL time.Duration
+
+	// ExpireTime is the time at which the secret is deleted.
+	// Only one of TTL and ExpireTime can be set.
+	ExpireTime time.Time
+
+	// Rotation is the rotation schedule of the secret, it requires Topics.
+	Rotation *Rotation
+
+	// Topics are the Pub/Sub topics notified on the changes of the secret,
+	// including the rotations, e.g. projects/<project>/topics/<topic>.
+	Topics []string
+}
+
+// Replica is a location a secret is replicated to.
+type Replica struct {
+	// Location is the location of the replica, e.g. `us-east1`.
+	Location string
+
+	// KMSKeyName is the Cloud KMS key used to encrypt the replica, it must be
+	// in the same location. Defaults to Google managed encryption.
+	KMSKeyName string
+}
+
+// Rotation is the rotation schedule of a secret.
+// Secret Manager publishes a message to the topics of the secret on each
+// rotation, the secret must be rotated by the subscriber.
+type Rotation struct {
+	// NextRotationTime is the time of the next rotation.
+	NextRotationTime time.Time
+
+	// Period is the duration between rotations, zero means the secret is
+	// rotated once at NextRotationTime.
+	Period time.Duration
+}
+
+// CreateSecretWithConfig is same as CreateSecret but it configures the secret
+// with config, e.g. its replication or its rotation schedule.
+func (c *SecretClient) CreateSecretWithConfig(name, value string, config SecretConfig) error {
+	return c.CreateSecretWithConfigContext(c.ctx, name, value, config)
+}
+
+// CreateSecretWithConfigContext is same as CreateSecretWithConfig but it
+// accepts a context and call options.
+func (c *SecretClient) CreateSecretWithConfigContext(
+	ctx context.Context,
+	name, value string,
+	config SecretConfig,
+	opts ...CallOption,
+) error {
+	secretPath := strings.Split(name, "/")
+	if len(secretPath) != minSecretPathLength {
+		return ferrors.NewInvalidArgumentError("secret name is not in expected format")
+	}
+
+	secret, err := config.secret()
+	if err != nil {
+		return err
+	}
+
+	// drop 'secrets' from the path
+	parent := strings.Join(secretPath[:len(secretPath)-2], "/")
+	secretName := secretPath[len(secretPath)-1]
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	createSecretRes, err := c.client.CreateSecret(
+		ctx,
+		&secretmanagerpb.CreateSecretRequest{
+			Parent:   parent,
+			SecretId: secretName,
+			Secret:   secret,
+		},
+		gaxOpts...,
+	)
+	if err != nil {
+		return wrapError(err, "unable to create secret: %s", name)
+	}
+
+	_, err = c.client.AddSecretVersion(
+		ctx,
+		&secretmanagerpb.AddSecretVersionRequest{
+			Parent: createSecretRes.GetName(),
+			Payload: &secretmanagerpb.SecretPayload{
+				Data:       []byte(value),
+				DataCrc32C: checksum([]byte(value)),
+			},
+		},
+		gaxOpts...,
+	)
+
+	return wrapError(err, "unable to attach the value to secret: %s", name)
+}
+
+// secret returns the secret configured by config.
+func (config SecretConfig) secret() (*secretmanagerpb.Secret, error) {
+	secret := &secretmanagerpb.Secret{
+		Labels:      config.Labels,
+		Annotations: config.Annotations,
+		Replication: config.replication(),
+	}
+
+	switch {
+	case config.TTL > 0 && !config.ExpireTime.IsZero():
+		return nil, ferrors.NewInvalidArgumentError("only one of TTL and ExpireTime can be set")
+	case config.TTL > 0:
+		secret.Expiration = &secretmanagerpb.Secret_Ttl{Ttl: durationpb.New(config.TTL)}
+	case !config.ExpireTime.IsZero():
+		secret.Expiration = &secretmanagerpb.Secret_ExpireTime{
+			ExpireTime: timestamppb.New(config.ExpireTime),
+		}
+	}
+
+	if config.Rotation != nil {
+		if len(config.Topics) == 0 {
+			return nil, ferrors.NewInvalidArgumentError("rotation requires at least a topic")
+		}
+		secret.Rotation = config.Rotation.rotation()
+	}
+
+	for _, topic := range config.Topics {
+		secret.Topics = append(secret.Topics, &secretmanagerpb.Topic{Name: topic})
+	}
+
+	return secret, nil
+}
+
+// replication returns the replication configured by config.
+func (config SecretConfig) replication() *secretmanagerpb.Replication {
+	if len(config.Replicas) == 0 {
+		automatic := &secretmanagerpb.Replication_Automatic{}
+		if config.KMSKeyName != "" {
+			automatic.CustomerManagedEncryption = &secretmanagerpb.CustomerManagedEncryption{
+				KmsKeyName: config.KMSKeyName,
+			}
+		}
+
+		return &secretmanagerpb.Replication{
+			Replication: &secretmanagerpb.Replication_Automatic_{Automatic: automatic},
+		}
+	}
+
+	replicas := make([]*secretmanagerpb.Replication_UserManaged_Replica, 0, len(config.Replicas))
+	for _, r := range config.Replicas {
+		replica := &secretmanagerpb.Replication_UserManaged_Replica{Location: r.Location}
+		if r.KMSKeyName != "" {
+			replica.CustomerManagedEncryption = &secretmanagerpb.CustomerManagedEncryption{
+				KmsKeyName: r.KMSKeyName,
+			}
+		}
+		replicas = append(replicas, replica)
+	}
+
+	return &secretmanagerpb.Replication{
+		Replication: &secretmanagerpb.Replication_UserManaged_{
+			UserManaged: &secretmanagerpb.Replication_UserManaged{Replicas: replicas},
+		},
+	}
+}
+
+// rotation returns the rotation schedule as a proto message.
+func (r *Rotation) rotation() *secretmanagerpb.Rotation {
+	rotation := &secretmanagerpb.Rotation{}
+	if !r.NextRotationTime.IsZero() {
+		rotation.NextRotationTime = timestamppb.New(r.NextRotationTime)
+	}
+	if r.Period > 0 {
+		rotation.RotationPeriod = durationpb.New(r.Period)
+	}
+	return rotation
+}
+
+// AddVersion adds a new version to the secret with the value, and returns
+// the name of the version.
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (c *SecretClient) AddVersion(name, value string) (string, error) {
+	return c.AddVersionContext(c.ctx, name, value)
+}
+
+// AddVersionContext is same as AddVersion but it accepts a context and call
+// options.
+func (c *SecretClient) AddVersionContext(
+	ctx context.Context,
+	name, value string,
+	opts ...CallOption,
+) (string, error) {
+	if len(strings.Split(name, "/")) != minSecretPathLength {
+		return "", ferrors.NewInvalidArgumentError("secret name is not in expected format")
+	}
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	version, err := c.client.AddSecretVersion(
+		ctx,
+		&secretmanagerpb.AddSecretVersionRequest{
+			Parent: name,
+			Payload: &secretmanagerpb.SecretPayload{
+				Data:       []byte(value),
+				DataCrc32C: checksum([]byte(value)),
+			},
+		},
+		gaxOpts...,
+	)
+	if err != nil {
+		return "", wrapError(err, "unable to add a version to secret: %s", name)
+	}
+
+	return version.GetName(), nil
+}
+
+// DisableVersion disables the secret version, it can no longer be accessed
+// until it is enabled again.
+// The expected name format is:
+// - projects/<project>/secrets/<name>/versions/<version>
+func (c *SecretClient) DisableVersion(name string) error {
+	return c.DisableVersionContext(c.ctx, name)
+}
+
+// DisableVersionContext is same as DisableVersion but it accepts a context and
+// call options.
+func (c *SecretClient) DisableVersionContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) error {
+	if err := validateVersionName(name); err != nil {
+		return err
+	}
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	_, err := c.client.DisableSecretVersion(
+		ctx,
+		&secretmanagerpb.DisableSecretVersionRequest{Name: name},
+		gaxOpts...,
+	)
+	return wrapError(err, "unable to disable secret version: %s", name)
+}
+
+// EnableVersion enables the disabled secret version.
+// The expected name format is:
+// - projects/<project>/secrets/<name>/versions/<version>
+func (c *SecretClient) EnableVersion(name string) error {
+	return c.EnableVersionContext(c.ctx, name)
+}
+
+// EnableVersionContext is same as EnableVersion but it accepts a context and
+// call options.
+func (c *SecretClient) EnableVersionContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) error {
+	if err := validateVersionName(name); err != nil {
+		return err
+	}
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	_, err := c.client.EnableSecretVersion(
+		ctx,
+		&secretmanagerpb.EnableSecretVersionRequest{Name: name},
+		gaxOpts...,
+	)
+	return wrapError(err, "unable to enable secret version: %s", name)
+}
+
+// DestroyVersion destroys the secret version, its value is deleted
+// permanently.
+// The expected name format is:
+// - projects/<project>/secrets/<name>/versions/<version>
+func (c *SecretClient) DestroyVersion(name string) error {
+	return c.DestroyVersionContext(c.ctx, name)
+}
+
+// DestroyVersionContext is same as DestroyVersion but it accepts a context and
+// call options.
+func (c *SecretClient) DestroyVersionContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) error {
+	if err := validateVersionName(name); err != nil {
+		return err
+	}
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	_, err := c.client.DestroySecretVersion(
+		ctx,
+		&secretmanagerpb.DestroySecretVersionRequest{Name: name},
+		gaxOpts...,
+	)
+	return wrapError(err, "unable to destroy secret version: %s", name)
+}
+
+// UpdateLabels replaces the labels of the secret.
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (c *SecretClient) UpdateLabels(name string, labels map[string]string) error {
+	return c.UpdateLabelsContext(c.ctx, name, labels)
+}
+
+// UpdateLabelsContext is same as UpdateLabels but it accepts a context and
+// call options.
+func (c *SecretClient) UpdateLabelsContext(
+	ctx context.Context,
+	name string,
+	labels map[string]string,
+	opts ...CallOption,
+) error {
+	return c.updateSecret(ctx, &secretmanagerpb.Secret{Name: name, Labels: labels}, "labels", opts)
+}
+
+// SetAnnotations replaces the annotations of the secret.
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (c *SecretClient) SetAnnotations(name string, annotations map[string]string) error {
+	return c.SetAnnotationsContext(c.ctx, name, annotations)
+}
+
+// SetAnnotationsContext is same as SetAnnotations but it accepts a context
+// and call options.
+func (c *SecretClient) SetAnnotationsContext(
+	ctx context.Context,
+	name string,
+	annotations map[string]string,
+	opts ...CallOption,
+) error {
+	secret := &secretmanagerpb.Secret{Name: name, Annotations: annotations}
+	return c.updateSecret(ctx, secret, "annotations", opts)
+}
+
+// UpdateRotation replaces the rotation schedule of the secret, nil removes
+// it. The secret must have topics to be rotated.
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (c *SecretClient) UpdateRotation(name string, rotation *Rotation) error {
+	return c.UpdateRotationContext(c.ctx, name, rotation)
+}
+
+// UpdateRotationContext is same as UpdateRotation but it accepts a context and
+// call options.
+func (c *SecretClient) UpdateRotationContext(
+	ctx context.Context,
+	name string,
+	rotation *Rotation,
+	opts ...CallOption,
+) error {
+	secret := &secretmanagerpb.Secret{Name: name}
+	if rotation != nil {
+		secret.Rotation = rotation.rotation()
+	}
+
+	return c.updateSecret(ctx, secret, "rotation", opts)
+}
+
+// updateSecret updates the field of the secret.
+func (c *SecretClient) updateSecret(
+	ctx context.Context,
+	secret *secretmanagerpb.Secret,
+	field string,
+	opts []CallOption,
+) error {
+	if len(strings.Split(secret.Name, "/")) != minSecretPathLength {
+		return ferrors.NewInvalidArgumentError("secret name is not in expected format")
+	}
+
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	_, err := c.client.UpdateSecret(
+		ctx,
+		&secretmanagerpb.UpdateSecretRequest{
+			Secret:     secret,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{field}},
+		},
+		gaxOpts...,
+	)
+	return wrapError(err, "unable to update the %s of secret: %s", field, secret.Name)
+}
+
+// validateVersionName returns an error if the name is not the name of a
+// secret version.
+func validateVersionName(name string) error {
+	secretPath := strings.Split(name, "/")
+	if len(secretPath) != minSecretPathLength+2 || secretPath[minSecretPathLength] != "versions" {
+		return ferrors.NewInvalidArgumentError("secret version name is not in expected format")
+	}
+	return nil
+}
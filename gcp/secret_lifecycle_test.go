@@ -0,0 +1,161 @@
+package gcp
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSecretConfig(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  SecretConfig
+		wantErr bool
+		check   func(t *testing.T, secret *secretmanagerpb.Secret)
+	}{
+		{
+			name:   "should replicate automatically by default",
+			config: SecretConfig{},
+			check: func(t *testing.T, secret *secretmanagerpb.Secret) {
+				if secret.Replication.GetAutomatic() == nil {
+					t.Errorf("expected automatic replication but, got = %v", secret.Replication)
+				}
+			},
+		},
+		{
+			name: "should replicate to the locations with their keys",
+			config: SecretConfig{
+				Replicas: []Replica{
+					{Location: "us-east1", KMSKeyName: "key"},
+					{Location: "us-west1"},
+				},
+			},
+			check: func(t *testing.T, secret *secretmanagerpb.Secret) {
+				replicas := secret.Replication.GetUserManaged().GetReplicas()
+				if len(replicas) != 2 || replicas[0].Location != "us-east1" ||
+					replicas[0].CustomerManagedEncryption.GetKmsKeyName() != "key" ||
+					replicas[1].CustomerManagedEncryption != nil {
+					t.Errorf("expected user managed replicas but, got = %v", replicas)
+				}
+			},
+		},
+		{
+			name: "should set the labels and the annotations",
+			config: SecretConfig{
+				Labels:      map[string]string{"team": "core"},
+				Annotations: map[string]string{"owner": "core"},
+			},
+			check: func(t *testing.T, secret *secretmanagerpb.Secret) {
+				if secret.Labels["team"] != "core" || secret.Annotations["owner"] != "core" {
+					t.Errorf("expected labels and annotations but, got = %v", secret)
+				}
+			},
+		},
+		{
+			name:   "should set the ttl",
+			config: SecretConfig{TTL: time.Hour},
+			check: func(t *testing.T, secret *secretmanagerpb.Secret) {
+				if secret.GetTtl().AsDuration() != time.Hour {
+					t.Errorf("expected ttl = 1h but, got = %v", secret.GetTtl())
+				}
+			},
+		},
+		{
+			name:    "should not accept both ttl and expire time",
+			config:  SecretConfig{TTL: time.Hour, ExpireTime: time.Now()},
+			wantErr: true,
+		},
+		{
+			name: "should set the rotation and the topics",
+			config: SecretConfig{
+				Rotation: &Rotation{Period: 24 * time.Hour},
+				Topics:   []string{"projects/p/topics/t"},
+			},
+			check: func(t *testing.T, secret *secretmanagerpb.Secret) {
+				if secret.Rotation.GetRotationPeriod().AsDuration() != 24*time.Hour ||
+					len(secret.Topics) != 1 {
+					t.Errorf("expected rotation and topics but, got = %v", secret)
+				}
+			},
+		},
+		{
+			name:    "should require topics to rotate",
+			config:  SecretConfig{Rotation: &Rotation{Period: time.Hour}},
+			wantErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := tc.config.secret()
+			if tc.wantErr {
+				if ferrors.Code(err) != ferrors.InvalidArgument {
+					t.Errorf("expected InvalidArgument error but, got = %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected error to be nil but, got error: %+v", err)
+			}
+			tc.check(t, secret)
+		})
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	notFound := status.Error(codes.NotFound, "secret not found")
+	err := wrapError(notFound, "unable to access secret: %s", "s")
+
+	if ferrors.Code(err) != ferrors.NotFound {
+		t.Errorf("expected code = NotFound but, got = %s", ferrors.Code(err))
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected status code = NotFound but, got = %s", status.Code(err))
+	}
+
+	if wrapError(nil, "message") != nil {
+		t.Errorf("expected nil error to stay nil")
+	}
+}
+
+func TestValidChecksum(t *testing.T) {
+	data := []byte("password")
+	wrong := *checksum(data) + 1
+
+	testCases := []struct {
+		name    string
+		payload *secretmanagerpb.SecretPayload
+		want    bool
+	}{
+		{
+			name:    "should accept payload without checksum",
+			payload: &secretmanagerpb.SecretPayload{Data: data},
+			want:    true,
+		},
+		{
+			name:    "should accept matching checksum",
+			payload: &secretmanagerpb.SecretPayload{Data: data, DataCrc32C: checksum(data)},
+			want:    true,
+		},
+		{
+			name:    "should reject corrupted payload",
+			payload: &secretmanagerpb.SecretPayload{Data: data, DataCrc32C: &wrong},
+			want:    false,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			if got := validChecksum(tc.payload); got != tc.want {
+				t.Errorf("expected = %v but, got = %v", tc.want, got)
+			}
+		})
+	}
+}
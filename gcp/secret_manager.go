@@ -3,14 +3,18 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
 	"regexp"
 	"strings"
+	"sync"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/status"
 )
 
 var versionsRegex = regexp.MustCompile(`/versions/.*$`)
@@ -24,76 +28,112 @@ type SecretVersion struct {
 // Expected format is
 // - projects/<project id or project name>/secrets/secret-name
 // - projects/<project id or project name>/secrets/secret-name/versions/<int>
+//
+// It uses the default client, see DefaultSecretClient.
 func ListSecretVersions(name string) ([]SecretVersion, error) {
-	if versionsRegex.MatchString(name) {
-		name = versionsRegex.ReplaceAllString(name, "")
+	client, err := DefaultSecretClient()
+	if err != nil {
+		return nil, err
 	}
 
-	var rtnMe []SecretVersion
+	return client.ListSecretVersions(name)
+}
 
-	// Create the client.
-	ctx := context.Background()
-	client, err := secretmanager.NewClient(ctx)
+// GetSecretByName gets a secret from gcp by its name
+// The expected format is [projects/*/secrets/*/versions/*]
+// for versions, you can use "latest" to grab the latest version
+//
+// It uses the default client, see DefaultSecretClient.
+func GetSecretByName(name string) (string, error) {
+	client, err := DefaultSecretClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create secretmanager client: %v", err)
+		return "", err
 	}
-	defer client.Close()
 
-	// Build the request.
-	req := &secretmanagerpb.ListSecretVersionsRequest{
-		Parent: name,
-	}
+	return client.GetSecret(name)
+}
 
-	// Call the API.
-	it := client.ListSecretVersions(ctx, req)
-	for {
-		resp, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
+// defaultClient is the process-wide client used by the package-level
+// functions.
+var defaultClient struct {
+	mu     sync.Mutex
+	client *SecretClient
+	opts   []option.ClientOption
+}
 
+// DefaultSecretClient returns the process-wide client used by the
+// package-level functions. It is created on first use, with the options given
+// to SetDefaultClientOptions or the Google Cloud ADC, and shares a single
+// connection between the calls.
+func DefaultSecretClient() (*SecretClient, error) {
+	defaultClient.mu.Lock()
+	defer defaultClient.mu.Unlock()
+
+	if defaultClient.client == nil {
+		client, err := NewSecretClient(defaultClient.opts...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list secret versions: %v", err)
+			return nil, err
 		}
-		rtnMe = append(rtnMe, SecretVersion{
-			Path:  resp.Name,
-			State: resp.State,
-		})
+		defaultClient.client = client
 	}
 
-	return rtnMe, nil
+	return defaultClient.client, nil
 }
 
-// GetSecretByName gets a secret from gcp by its name
-// The expected format is [projects/*/secrets/*/versions/*]
-// for versions, you can use "latest" to grab the latest version
-func GetSecretByName(name string) (string, error) {
-	ctx := context.Background()
-	client, err := secretmanager.NewClient(ctx)
-	if err != nil {
-		return "", err
+// SetDefaultClientOptions configures the options of the default client, e.g.
+// the credentials. It must be called before the default client is used, e.g.
+// at startup, the next call creates the client with the options.
+//
+// It returns a FailedPrecondition error if the default client is already
+// created, since closing it would break the callers still holding it. Call
+// CloseDefaultSecretClient first once they are done to replace it.
+func SetDefaultClientOptions(opts ...option.ClientOption) error {
+	defaultClient.mu.Lock()
+	defer defaultClient.mu.Unlock()
+
+	if defaultClient.client != nil {
+		return ferrors.WithCode(ferrors.FailedPrecondition,
+			"default secret client is already in use, close it first")
 	}
-	defer client.Close()
-	// Build the request.
 
-	req := &secretmanagerpb.AccessSecretVersionRequest{
-		Name: name,
-	}
+	defaultClient.opts = opts
+	return nil
+}
 
-	// Call the API.
-	result, err := client.AccessSecretVersion(ctx, req)
-	if err != nil {
-		return "", err
+// CloseDefaultSecretClient closes the connection of the default client.
+// The next call of a package-level function creates a new one.
+func CloseDefaultSecretClient() error {
+	defaultClient.mu.Lock()
+	defer defaultClient.mu.Unlock()
+
+	return closeDefaultClient()
+}
+
+// closeDefaultClient closes the default client.
+// NOTE: defaultClient.mu must be held.
+func closeDefaultClient() error {
+	if defaultClient.client == nil {
+		return nil
 	}
 
-	return string(result.Payload.Data), nil
+	err := defaultClient.client.Close()
+	defaultClient.client = nil
+
+	return ferrors.Wrap(err, "unable to close secret manager client")
 }
 
 // SecretClient is a wrapper around GCP Secret Service.
 // It provides useful helpers to for getting and creating secrets.
+//
+// The methods ending with Context accept a context and call options, the
+// others use a background context and the default call options of the
+// client.
 type SecretClient struct {
 	client *secretmanager.Client
 	ctx    context.Context
+
+	// callOpts are applied to every call before the options of the call.
+	callOpts []CallOption
 }
 
 const minSecretPathLength = 4
@@ -117,6 +157,37 @@ func NewSecretClient(opts ...option.ClientOption) (*SecretClient, error) {
 	}, nil
 }
 
+// WithCallOptions returns a copy of the client which applies the options to
+// every call, e.g. a default timeout. The options of a call are applied after
+// them.
+//
+// The copy shares the connection of the client, closing either of them closes
+// both.
+func (c *SecretClient) WithCallOptions(opts ...CallOption) *SecretClient {
+	callOpts := make([]CallOption, 0, len(c.callOpts)+len(opts))
+	callOpts = append(callOpts, c.callOpts...)
+	callOpts = append(callOpts, opts...)
+
+	return &SecretClient{
+		client:   c.client,
+		ctx:      c.ctx,
+		callOpts: callOpts,
+	}
+}
+
+// call prepares a call with the default call options of the client and the
+// options of the call.
+func (c *SecretClient) call(
+	ctx context.Context,
+	opts []CallOption,
+) (context.Context, context.CancelFunc, []gax.CallOption) {
+	callOpts := make([]CallOption, 0, len(c.callOpts)+len(opts))
+	callOpts = append(callOpts, c.callOpts...)
+	callOpts = append(callOpts, opts...)
+
+	return buildCallOptions(callOpts...).apply(ctx)
+}
+
 // GetSecret fetches the secret from GCP Secret Manager and return it as
 // a string.
 //
@@ -124,87 +195,164 @@ func NewSecretClient(opts ...option.ClientOption) (*SecretClient, error) {
 // - projects/<project>/secrets/<name>/versions/<version>
 // - projects/<project>/secrets/<name>/versions/latest
 func (c *SecretClient) GetSecret(name string) (string, error) {
+	return c.GetSecretContext(c.ctx, name)
+}
+
+// GetSecretContext is same as GetSecret but it accepts a context and call
+// options.
+func (c *SecretClient) GetSecretContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) (string, error) {
+	secret, _, err := c.GetSecretWithVersionContext(ctx, name, opts...)
+	return secret, err
+}
+
+// GetSecretWithVersion is same as GetSecret but it returns the name of the
+// version that was accessed as well, e.g. `latest` is resolved to
+// projects/<project number>/secrets/<name>/versions/<number>.
+func (c *SecretClient) GetSecretWithVersion(name string) (string, string, error) {
+	return c.GetSecretWithVersionContext(c.ctx, name)
+}
+
+// GetSecretWithVersionContext is same as GetSecretWithVersion but it accepts
+// a context and call options.
+func (c *SecretClient) GetSecretWithVersionContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) (string, string, error) {
 	secretPath := strings.Split(name, "/")
 	if len(secretPath) < minSecretPathLength {
-		return "", ferrors.New("secret name is not in expected format")
+		return "", "", ferrors.NewInvalidArgumentError("secret name is not in expected format")
 	}
 
 	if len(secretPath) == minSecretPathLength {
 		secretPath = append(secretPath, "versions", "latest")
 	}
 
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
 	res, err := c.client.AccessSecretVersion(
-		c.ctx,
+		ctx,
 		&secretmanagerpb.AccessSecretVersionRequest{
 			Name: strings.Join(secretPath, "/"),
 		},
+		gaxOpts...,
 	)
 	if err != nil {
-		return "", err
+		return "", "", wrapError(err, "unable to access secret: %s", name)
+	}
+
+	if !validChecksum(res.Payload) {
+		return "", "", ferrors.WithCode(
+			ferrors.Internal,
+			fmt.Sprintf("checksum of secret %s does not match its payload", res.Name),
+		)
 	}
 
-	return string(res.Payload.Data), nil
+	return string(res.Payload.Data), res.Name, nil
 }
 
-// CreateSecret creates a secret in GCP Secret Service.
-// The expected name format is:
+// ListSecretVersions returns all the versions of the secret and their state.
+//
+// The expected formats are:
 // - projects/<project>/secrets/<name>
-func (c *SecretClient) CreateSecret(name, value string) error {
-	secretPath := strings.Split(name, "/")
-	if len(secretPath) < minSecretPathLength {
-		return ferrors.New("secret name is not in expected format")
-	}
-
-	// drop 'secrets' from the path
-	parent := strings.Join(secretPath[:len(secretPath)-2], "/")
-	secretName := secretPath[len(secretPath)-1]
-
-	createSecretRes, err := c.client.CreateSecret(
-		c.ctx,
-		&secretmanagerpb.CreateSecretRequest{
-			Parent:   parent,
-			SecretId: secretName,
-			Secret: &secretmanagerpb.Secret{
-				Replication: &secretmanagerpb.Replication{
-					Replication: &secretmanagerpb.Replication_Automatic_{
-						Automatic: &secretmanagerpb.Replication_Automatic{},
-					},
-				},
-			},
-		},
-	)
-	if err != nil {
-		return ferrors.Wrapf(err, "unable to create secret: %s", name)
+// - projects/<project>/secrets/<name>/versions/<version>
+func (c *SecretClient) ListSecretVersions(name string) ([]SecretVersion, error) {
+	return c.ListSecretVersionsContext(c.ctx, name)
+}
+
+// ListSecretVersionsContext is same as ListSecretVersions but it accepts a
+// context and call options.
+func (c *SecretClient) ListSecretVersionsContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) ([]SecretVersion, error) {
+	name = versionsRegex.ReplaceAllString(name, "")
+	if len(strings.Split(name, "/")) != minSecretPathLength {
+		return nil, ferrors.NewInvalidArgumentError("secret name is not in expected format")
 	}
 
-	_, err = c.client.AddSecretVersion(
-		c.ctx,
-		&secretmanagerpb.AddSecretVersionRequest{
-			Parent: createSecretRes.GetName(),
-			Payload: &secretmanagerpb.SecretPayload{
-				Data: []byte(value),
-			},
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
+	var versions []SecretVersion
+
+	it := c.client.ListSecretVersions(
+		ctx,
+		&secretmanagerpb.ListSecretVersionsRequest{
+			Parent: name,
 		},
+		gaxOpts...,
 	)
+	for {
+		resp, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapError(err, "unable to list secret versions: %s", name)
+		}
+
+		versions = append(versions, SecretVersion{
+			Path:  resp.Name,
+			State: resp.State,
+		})
+	}
+
+	return versions, nil
+}
+
+// CreateSecret creates a secret in GCP Secret Service.
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+func (c *SecretClient) CreateSecret(name, value string) error {
+	return c.CreateSecretContext(c.ctx, name, value)
+}
 
-	return ferrors.Wrapf(err, "unable to attach the value to secret: %s", name)
+// CreateSecretContext is same as CreateSecret but it accepts a context and
+// call options.
+func (c *SecretClient) CreateSecretContext(
+	ctx context.Context,
+	name, value string,
+	opts ...CallOption,
+) error {
+	return c.CreateSecretWithConfigContext(ctx, name, value, SecretConfig{}, opts...)
 }
 
 // DeleteSecret deletes a secret.
 // - projects/<project>/secrets/<name>
 func (c *SecretClient) DeleteSecret(name string) error {
+	return c.DeleteSecretContext(c.ctx, name)
+}
+
+// DeleteSecretContext is same as DeleteSecret but it accepts a context and
+// call options.
+func (c *SecretClient) DeleteSecretContext(
+	ctx context.Context,
+	name string,
+	opts ...CallOption,
+) error {
 	secretPath := strings.Split(name, "/")
 	if len(secretPath) < minSecretPathLength {
-		return ferrors.New("secret name is not in expected format")
+		return ferrors.NewInvalidArgumentError("secret name is not in expected format")
 	}
 
+	ctx, cancel, gaxOpts := c.call(ctx, opts)
+	defer cancel()
+
 	err := c.client.DeleteSecret(
-		c.ctx,
+		ctx,
 		&secretmanagerpb.DeleteSecretRequest{
 			Name: name,
 		},
+		gaxOpts...,
 	)
-	return ferrors.Wrapf(err, "unable to delete secret: %s", name)
+	return wrapError(err, "unable to delete secret: %s", name)
 }
 
 // Close closes the connection to the GCP Secret Service.
@@ -212,3 +360,37 @@ func (c *SecretClient) DeleteSecret(name string) error {
 func (c *SecretClient) Close() error {
 	return c.client.Close()
 }
+
+// wrapError wraps the error of a call to GCP Secret Manager with the message,
+// as a ferror with the code of its gRPC status, e.g. ferrors.NotFound.
+func wrapError(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return ferrors.Wrapf(err, format, args...)
+	}
+
+	return ferrors.Wrapf(ferrors.WithCode(ferrors.ErrorCode(st.Code()), st.Message()), format, args...)
+}
+
+// crc32c is the table of the CRC32C checksums of the payloads.
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// checksum returns the CRC32C checksum of the data, as expected by GCP Secret
+// Manager.
+func checksum(data []byte) *int64 {
+	crc := int64(crc32.Checksum(data, crc32c))
+	return &crc
+}
+
+// validChecksum reports whether the payload matches its checksum, if it has
+// one.
+func validChecksum(payload *secretmanagerpb.SecretPayload) bool {
+	if payload.DataCrc32C == nil {
+		return true
+	}
+	return payload.GetDataCrc32C() == *checksum(payload.GetData())
+}
@@ -0,0 +1,230 @@
+package gcp
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+// defaultWatchInterval is the default polling interval of Watch.
+const defaultWatchInterval = time.Minute
+
+// WatchOption configures SecretClient.Watch.
+type WatchOption func(*watchOptions)
+
+// watchOptions configures SecretClient.Watch.
+// NOTE: Don't use it directly.
+type watchOptions struct {
+	interval time.Duration
+	onError  func(err error)
+	callOpts []CallOption
+}
+
+// WithWatchInterval configures how often the versions of the secret are
+// polled. Defaults to 1m.
+func WithWatchInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = interval
+	}
+}
+
+// WithWatchErrorHandler configures a function called when polling the secret
+// fails. The watch keeps polling after an error.
+func WithWatchErrorHandler(onError func(err error)) WatchOption {
+	return func(o *watchOptions) {
+		o.onError = onError
+	}
+}
+
+// WithWatchCallOptions configures the options of the calls made while polling
+// the secret.
+func WithWatchCallOptions(opts ...CallOption) WatchOption {
+	return func(o *watchOptions) {
+		o.callOpts = append(o.callOpts, opts...)
+	}
+}
+
+// versionReader lists and reads the versions of a secret, see SecretClient.
+// It is faked in tests.
+type versionReader interface {
+	ListSecretVersionsContext(
+		ctx context.Context,
+		name string,
+		opts ...CallOption,
+	) ([]SecretVersion, error)
+	GetSecretWithVersionContext(
+		ctx context.Context,
+		name string,
+		opts ...CallOption,
+	) (string, string, error)
+}
+
+func buildWatchOptions(opts ...WatchOption) *watchOptions {
+	o := &watchOptions{
+		interval: defaultWatchInterval,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	return o
+}
+
+// Watch polls the versions of the secret and calls onChange with the old and
+// the new value whenever its latest enabled version changes, i.e. a new
+// version is added or the latest one is disabled or destroyed. So rotated
+// secrets, e.g. database passwords, can be reloaded without restarting.
+//
+// The new value is empty if the secret has no enabled version anymore.
+// onChange is not called for the initial value, which is returned by the
+// first poll; use GetSecret to read it.
+//
+// Watch blocks until the context is done and returns the error of the
+// context, or the error of the first poll if it fails. The errors of the
+// following polls are passed to the error handler, see WithWatchErrorHandler.
+//
+// The expected name format is:
+// - projects/<project>/secrets/<name>
+//
+// Example:
+//
+//	go client.Watch(ctx, "projects/p/secrets/db-password", func(old, new string) {
+//		db.SetPassword(new)
+//	}, gcp.WithWatchInterval(30*time.Second))
+func (c *SecretClient) Watch(
+	ctx context.Context,
+	name string,
+	onChange func(old, new string),
+	opts ...WatchOption,
+) error {
+	return watch(ctx, c, name, onChange, buildWatchOptions(opts...))
+}
+
+// watch polls the versions of the secret with the reader, see
+// SecretClient.Watch.
+func watch(
+	ctx context.Context,
+	r versionReader,
+	name string,
+	onChange func(old, new string),
+	o *watchOptions,
+) error {
+	if o.interval <= 0 {
+		return ferrors.NewInvalidArgumentError("watch interval must be positive")
+	}
+
+	current, err := latestEnabledVersion(ctx, r, name, o.callOpts)
+	if err != nil {
+		return err
+	}
+
+	value, err := versionValue(ctx, r, current, o.callOpts)
+	if err != nil {
+		return err
+	}
+
+	handleError := func(err error) {
+		if ctx.Err() == nil && o.onError != nil {
+			o.onError(err)
+		}
+	}
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		version, err := latestEnabledVersion(ctx, r, name, o.callOpts)
+		if err != nil {
+			handleError(err)
+			continue
+		}
+
+		// the value is accessed only when the version changes, since the
+		// accesses are billed.
+		if version == current {
+			continue
+		}
+
+		newValue, err := versionValue(ctx, r, version, o.callOpts)
+		if err != nil {
+			handleError(err)
+			continue
+		}
+
+		old := value
+		current, value = version, newValue
+		if old != newValue {
+			onChange(old, newValue)
+		}
+	}
+}
+
+// latestEnabledVersion returns the name of the latest enabled version of the
+// secret, or an empty name if no version is enabled.
+func latestEnabledVersion(
+	ctx context.Context,
+	r versionReader,
+	name string,
+	opts []CallOption,
+) (string, error) {
+	versions, err := r.ListSecretVersionsContext(ctx, name, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	latest, latestNumber := "", -1
+	for _, v := range versions {
+		if v.State != secretmanagerpb.SecretVersion_ENABLED {
+			continue
+		}
+
+		if n := versionNumber(v.Path); n > latestNumber {
+			latest, latestNumber = v.Path, n
+		}
+	}
+
+	return latest, nil
+}
+
+// versionValue returns the value of the secret version, or an empty value if
+// the version name is empty.
+func versionValue(
+	ctx context.Context,
+	r versionReader,
+	version string,
+	opts []CallOption,
+) (string, error) {
+	if version == "" {
+		return "", nil
+	}
+
+	value, _, err := r.GetSecretWithVersionContext(ctx, version, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// versionNumber returns the number of the secret version, or zero if the name
+// does not end with a number.
+func versionNumber(name string) int {
+	n, err := strconv.Atoi(name[strings.LastIndex(name, "/")+1:])
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,242 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/Flahmingo-Investments/helpers-go/ferrors"
+)
+
+const watchedSecret = "projects/p/secrets/s"
+
+// fakeVersions is a versionReader serving the versions set by the test.
+type fakeVersions struct {
+	mu       sync.Mutex
+	versions []SecretVersion
+	values   map[string]string
+	err      error
+	polls    int
+	accesses int
+}
+
+func (f *fakeVersions) ListSecretVersionsContext(
+	context.Context,
+	string,
+	...CallOption,
+) ([]SecretVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.polls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return append([]SecretVersion(nil), f.versions...), nil
+}
+
+func (f *fakeVersions) GetSecretWithVersionContext(
+	_ context.Context,
+	name string,
+	_ ...CallOption,
+) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.accesses++
+	return f.values[name], name, nil
+}
+
+// set sets the state of the version and its value.
+func (f *fakeVersions) set(number, value string, state secretmanagerpb.SecretVersion_State) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := watchedSecret + "/versions/" + number
+	f.values[name] = value
+
+	for i := range f.versions {
+		if f.versions[i].Path == name {
+			f.versions[i].State = state
+			return
+		}
+	}
+	f.versions = append(f.versions, SecretVersion{Path: name, State: state})
+}
+
+// await waits until the versions are polled twice, so the current state is
+// seen by at least one complete poll.
+func (f *fakeVersions) await(t *testing.T) {
+	t.Helper()
+
+	f.mu.Lock()
+	want := f.polls + 2
+	f.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		polls := f.polls
+		f.mu.Unlock()
+
+		if polls >= want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected the versions to be polled")
+}
+
+func (f *fakeVersions) fail(err error) {
+	f.mu.Lock()
+	f.err = err
+	f.mu.Unlock()
+}
+
+func TestWatch(t *testing.T) {
+	const (
+		enabled  = secretmanagerpb.SecretVersion_ENABLED
+		disabled = secretmanagerpb.SecretVersion_DISABLED
+	)
+
+	fake := &fakeVersions{values: map[string]string{}}
+	fake.set("1", "a", enabled)
+	fake.set("2", "b", disabled)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type change struct{ old, new string }
+	changes := make(chan change, 10)
+	errs := make(chan error, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- watch(ctx, fake, watchedSecret, func(old, new string) {
+			changes <- change{old, new}
+		}, buildWatchOptions(
+			WithWatchInterval(time.Millisecond),
+			WithWatchErrorHandler(func(err error) { errs <- err }),
+		))
+	}()
+
+	expectChange := func(want change) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("expected change = %v but, got = %v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected change = %v", want)
+		}
+	}
+
+	// let the watch read the initial version.
+	fake.await(t)
+
+	fake.set("3", "c", enabled)
+	expectChange(change{"a", "c"})
+
+	// versions are ordered by number, not by name.
+	fake.set("10", "d", enabled)
+	expectChange(change{"c", "d"})
+
+	unavailable := ferrors.WithCode(ferrors.Unavailable, "unavailable")
+	fake.fail(unavailable)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, unavailable) {
+			t.Errorf("expected error = %v but, got = %v", unavailable, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the error handler to be called")
+	}
+	fake.fail(nil)
+
+	// a new version with the same value is not a change.
+	fake.set("11", "d", enabled)
+	fake.await(t)
+	fake.set("10", "d", disabled)
+	fake.set("3", "c", disabled)
+	fake.set("1", "a", disabled)
+	fake.set("11", "d", disabled)
+	expectChange(change{"d", ""})
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled but, got = %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("expected no more changes but, got = %v", <-changes)
+	}
+}
+
+func TestWatchAccessesChangedVersions(t *testing.T) {
+	fake := &fakeVersions{values: map[string]string{}}
+	fake.set("1", "a", secretmanagerpb.SecretVersion_ENABLED)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, fake, watchedSecret, func(old, new string) {},
+			buildWatchOptions(WithWatchInterval(time.Millisecond)))
+	}()
+
+	fake.await(t)
+	fake.await(t)
+
+	fake.mu.Lock()
+	accesses := fake.accesses
+	fake.mu.Unlock()
+
+	if accesses != 1 {
+		t.Errorf("expected the value to be accessed once but, got = %d accesses", accesses)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWatchErrors(t *testing.T) {
+	fake := &fakeVersions{values: map[string]string{}}
+	onChange := func(old, new string) {}
+
+	err := watch(context.Background(), fake, watchedSecret, onChange,
+		buildWatchOptions(WithWatchInterval(0)))
+	if ferrors.Code(err) != ferrors.InvalidArgument {
+		t.Errorf("expected InvalidArgument error but, got = %v", err)
+	}
+
+	notFound := ferrors.NewNotFoundError("secret not found")
+	fake.fail(notFound)
+
+	err = watch(context.Background(), fake, watchedSecret, onChange, buildWatchOptions())
+	if !errors.Is(err, notFound) {
+		t.Errorf("expected the error of the first poll but, got = %v", err)
+	}
+}
+
+func TestVersionNumber(t *testing.T) {
+	testCases := []struct {
+		name string
+		want int
+	}{
+		{name: watchedSecret + "/versions/12", want: 12},
+		{name: watchedSecret + "/versions/latest", want: 0},
+		{name: "12", want: 12},
+		{name: "", want: 0},
+	}
+
+	for _, tc := range testCases {
+		if got := versionNumber(tc.name); got != tc.want {
+			t.Errorf("expected versionNumber(%q) = %d but, got = %d", tc.name, tc.want, got)
+		}
+	}
+}